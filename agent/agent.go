@@ -19,6 +19,7 @@ type Agent struct {
 	PluginGenerators   []metrics.PluginGenerator
 	Checkers           []*checks.Checker
 	MetadataGenerators []*metadata.Generator
+	Stats              *metrics.AgentStats
 }
 
 // MetricsResult XXX
@@ -44,6 +45,12 @@ func (agent *Agent) Watch(ctx context.Context) chan *MetricsResult {
 	ticker := make(chan time.Time)
 	interval := config.PostMetricsInterval
 
+	for _, g := range agent.MetricsGenerators {
+		if s, ok := g.(*metrics.SamplingGenerator); ok {
+			go s.Run(ctx)
+		}
+	}
+
 	go func() {
 		t := time.NewTicker(1 * time.Second)
 
@@ -62,31 +69,27 @@ func (agent *Agent) Watch(ctx context.Context) chan *MetricsResult {
 				// fire an event if t - last is more than 1 minute
 				if t.Second()%int(interval.Seconds()) == 0 || t.After(last.Add(interval)) {
 					// Non-blocking send of time.
-					// If `collectMetrics` runs with max concurrency, we drop ticks.
+					// If `collectMetrics` is still running, we drop ticks.
 					// Because the time is used as agent.MetricsResult.Created.
 					select {
 					case ticker <- t:
 						last = t
 					default:
+						agent.Stats.RecordDroppedTick()
 					}
 				}
 			}
 		}
 	}()
 
-	const collectMetricsWorkerMax = 3
-
 	go func() {
-		// Start collectMetrics concurrently
-		// so that it does not prevent running next collectMetrics.
-		sem := make(chan struct{}, collectMetricsWorkerMax)
+		// Collect metrics one at a time. Built-in generators no longer sleep
+		// for an interval to calculate rates, so a collection overlaps the next
+		// tick only while waiting for a slow metrics plugin, and overlapping
+		// collections would make stateful generators calculate rates from
+		// samples out of order. The ticks during such a collection are dropped.
 		for tickedTime := range ticker {
-			ti := tickedTime
-			sem <- struct{}{}
-			go func() {
-				metricsResult <- agent.CollectMetrics(ti)
-				<-sem
-			}()
+			metricsResult <- agent.CollectMetrics(tickedTime)
 		}
 	}()
 
@@ -97,6 +100,13 @@ func (agent *Agent) Watch(ctx context.Context) chan *MetricsResult {
 func (agent *Agent) CollectGraphDefsOfPlugins() []*mkr.GraphDefsParam {
 	payloads := []*mkr.GraphDefsParam{}
 
+	for _, g := range agent.MetricsGenerators {
+		if s, ok := g.(*metrics.SamplingGenerator); ok {
+			p, _ := s.PrepareGraphDefs()
+			payloads = append(payloads, p...)
+		}
+	}
+
 	for _, g := range agent.PluginGenerators {
 		p, err := g.PrepareGraphDefs()
 
@@ -115,13 +125,16 @@ func (agent *Agent) CollectGraphDefsOfPlugins() []*mkr.GraphDefsParam {
 }
 
 // InitPluginGenerators XXX
-func (agent *Agent) InitPluginGenerators(api *mackerel.API) {
+func (agent *Agent) InitPluginGenerators(api *mackerel.API) error {
 	payloads := agent.CollectGraphDefsOfPlugins()
 
+	var err error
 	if len(payloads) > 0 {
-		err := api.CreateGraphDefs(payloads)
+		err = api.CreateGraphDefs(payloads)
 		if err != nil {
 			logger.Errorf("Failed to create graphdefs: %s", err)
 		}
 	}
+	agent.Stats.RecordGraphDefs(err)
+	return err
 }
@@ -0,0 +1,208 @@
+package checks
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mackerelio/mackerel-agent/config"
+	"github.com/mackerelio/mackerel-agent/metrics"
+	"github.com/mackerelio/mackerel-agent/util"
+)
+
+/*
+check TLS certificates
+
+`custom.certificate.{check}.{target}`: The number of days until the earliest expiration of the certificate chain
+
+check = the name of the check (`plugin.checks.{check}`)
+target = the sanitized file path or endpoint ("host:port")
+
+The check reports WARNING or CRITICAL when the number of days is less than
+warning_days (default 30) or critical_days (default 14). It also reports
+CRITICAL when the certificate chain or the hostname cannot be verified,
+unless skip_verify is set.
+*/
+
+const (
+	defaultCertificateWarningDays  = 30
+	defaultCertificateCriticalDays = 14
+)
+
+type certificateProber struct {
+	name    string
+	config  *config.CertificateCheck
+	timeout time.Duration
+}
+
+func (p *certificateProber) String() string {
+	return fmt.Sprintf("certificate files=%v endpoints=%v", p.config.Files, p.config.Endpoints)
+}
+
+// GraphDefs implements Prober.
+func (p *certificateProber) GraphDefs() map[string]metrics.CustomGraphDef {
+	return map[string]metrics.CustomGraphDef{
+		"certificate.#": {
+			Label: "Certificate Days Until Expiration",
+			Unit:  "float",
+			Metrics: []metrics.CustomGraphMetricDef{
+				{Name: "*", Label: "%1"},
+			},
+		},
+	}
+}
+
+// Probe implements Prober.
+func (p *certificateProber) Probe() *ProbeResult {
+	now := time.Now()
+	roots, err := p.loadRoots()
+	if err != nil {
+		return &ProbeResult{Status: StatusUnknown, Message: err.Error()}
+	}
+
+	result := &ProbeResult{Status: StatusOK, Values: metrics.Values{}}
+	var messages []string
+	inspect := func(target string, certs []*x509.Certificate, serverName string, err error) {
+		status, msg := StatusCritical, ""
+		if err != nil {
+			msg = err.Error()
+		} else {
+			var days float64
+			status, msg, days = p.inspect(certs, serverName, roots, now)
+			result.Values["custom.certificate."+util.SanitizeMetricKey(p.name)+"."+util.SanitizeMetricKey(target)] = days
+		}
+		result.Status = worseStatus(result.Status, status)
+		messages = append(messages, fmt.Sprintf("%s %s: %s", status, target, msg))
+	}
+	for _, file := range p.config.Files {
+		certs, err := readCertificateFile(file)
+		inspect(file, certs, p.config.ServerName, err)
+	}
+	for _, endpoint := range p.config.Endpoints {
+		certs, serverName, err := p.fetchCertificates(endpoint)
+		inspect(endpoint, certs, serverName, err)
+	}
+	result.Message = strings.Join(messages, "\n")
+	return result
+}
+
+// inspect verifies certs, whose first element is the leaf certificate,
+// and returns the status along with the days until the earliest expiration.
+func (p *certificateProber) inspect(certs []*x509.Certificate, serverName string, roots *x509.CertPool, now time.Time) (Status, string, float64) {
+	expiring := certs[0]
+	for _, cert := range certs[1:] {
+		if cert.NotAfter.Before(expiring.NotAfter) {
+			expiring = cert
+		}
+	}
+	days := expiring.NotAfter.Sub(now).Hours() / 24
+	msg := fmt.Sprintf("%q expires in %.1f days (%s)", expiring.Subject.CommonName, days, expiring.NotAfter.Format(time.RFC3339))
+
+	if !p.config.SkipVerify {
+		intermediates := x509.NewCertPool()
+		for _, cert := range certs[1:] {
+			intermediates.AddCert(cert)
+		}
+		_, err := certs[0].Verify(x509.VerifyOptions{
+			DNSName:       serverName,
+			Roots:         roots,
+			Intermediates: intermediates,
+			CurrentTime:   now,
+		})
+		if err != nil {
+			return StatusCritical, fmt.Sprintf("verification failed: %s; %s", err, msg), days
+		}
+	}
+
+	warningDays, criticalDays := defaultCertificateWarningDays, defaultCertificateCriticalDays
+	if p.config.WarningDays != nil {
+		warningDays = *p.config.WarningDays
+	}
+	if p.config.CriticalDays != nil {
+		criticalDays = *p.config.CriticalDays
+	}
+	switch {
+	case days < float64(criticalDays):
+		return StatusCritical, msg, days
+	case days < float64(warningDays):
+		return StatusWarning, msg, days
+	}
+	return StatusOK, msg, days
+}
+
+func (p *certificateProber) loadRoots() (*x509.CertPool, error) {
+	if p.config.CAFile == "" {
+		return nil, nil // use the system roots
+	}
+	b, err := os.ReadFile(p.config.CAFile)
+	if err != nil {
+		return nil, err
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(b) {
+		return nil, fmt.Errorf("no certificate found in %s", p.config.CAFile)
+	}
+	return roots, nil
+}
+
+// fetchCertificates returns the certificate chain presented by the endpoint
+// and the server name used for SNI.
+func (p *certificateProber) fetchCertificates(endpoint string) ([]*x509.Certificate, string, error) {
+	host, _, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return nil, "", err
+	}
+	serverName := p.config.ServerName
+	if serverName == "" {
+		serverName = host
+	}
+	dialer := &net.Dialer{Timeout: p.timeout}
+	conn, err := tls.DialWithDialer(dialer, "tcp", endpoint, &tls.Config{
+		ServerName: serverName,
+		// The chain is verified in inspect so that the expiration can be
+		// reported even if the verification fails.
+		InsecureSkipVerify: true, // nolint:gosec
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	defer conn.Close()
+
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil, "", fmt.Errorf("no certificate presented")
+	}
+	return certs, serverName, nil
+}
+
+func readCertificateFile(file string) ([]*x509.Certificate, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, b = pem.Decode(b)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificate found in %s", file)
+	}
+	return certs, nil
+}
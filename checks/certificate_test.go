@@ -0,0 +1,140 @@
+package checks
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mackerelio/mackerel-agent/config"
+)
+
+func newTLSServerWithCAFile(t *testing.T) (*httptest.Server, string) {
+	t.Helper()
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(ts.Close)
+
+	file := filepath.Join(t.TempDir(), "ca.pem")
+	b := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+	if err := os.WriteFile(file, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return ts, file
+}
+
+func TestCertificateProber_Endpoint(t *testing.T) {
+	ts, caFile := newTLSServerWithCAFile(t)
+	endpoint := ts.Listener.Addr().String()
+	large := 1000000
+
+	tests := []struct {
+		name   string
+		config config.CertificateCheck
+		status Status
+	}{
+		{
+			name:   "ok",
+			config: config.CertificateCheck{Endpoints: []string{endpoint}, CAFile: caFile},
+			status: StatusOK,
+		},
+		{
+			name:   "ok with server_name",
+			config: config.CertificateCheck{Endpoints: []string{endpoint}, CAFile: caFile, ServerName: "example.com"},
+			status: StatusOK,
+		},
+		{
+			name:   "warning",
+			config: config.CertificateCheck{Endpoints: []string{endpoint}, CAFile: caFile, WarningDays: &large},
+			status: StatusWarning,
+		},
+		{
+			name:   "critical",
+			config: config.CertificateCheck{Endpoints: []string{endpoint}, CAFile: caFile, WarningDays: &large, CriticalDays: &large},
+			status: StatusCritical,
+		},
+		{
+			name:   "unknown authority",
+			config: config.CertificateCheck{Endpoints: []string{endpoint}},
+			status: StatusCritical,
+		},
+		{
+			name:   "unknown authority but skip_verify",
+			config: config.CertificateCheck{Endpoints: []string{endpoint}, SkipVerify: true},
+			status: StatusOK,
+		},
+		{
+			name:   "hostname mismatch",
+			config: config.CertificateCheck{Endpoints: []string{endpoint}, CAFile: caFile, ServerName: "mackerel.invalid"},
+			status: StatusCritical,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewProber("cert", &config.CheckPlugin{Certificate: &tt.config}, nil)
+			result := p.Probe()
+			if result.Status != tt.status {
+				t.Errorf("status should be %s but %s: %s", tt.status, result.Status, result.Message)
+			}
+			key := "custom.certificate.cert." + strings.NewReplacer(".", "_", ":", "_").Replace(endpoint)
+			days, ok := result.Values[key]
+			if !ok {
+				t.Fatalf("%s should be reported: %v", key, result.Values)
+			}
+			if days < 365 {
+				t.Errorf("days until expiration should be more than a year: %f", days)
+			}
+		})
+	}
+}
+
+func TestCertificateProber_File(t *testing.T) {
+	_, caFile := newTLSServerWithCAFile(t)
+
+	p := NewProber("cert", &config.CheckPlugin{Certificate: &config.CertificateCheck{
+		Files:      []string{caFile, filepath.Join(t.TempDir(), "missing.pem")},
+		SkipVerify: true,
+	}}, nil)
+	result := p.Probe()
+	if result.Status != StatusCritical {
+		t.Errorf("status should be CRITICAL because of the missing file but %s", result.Status)
+	}
+	if len(result.Values) != 1 {
+		t.Errorf("only the existing file should be reported: %v", result.Values)
+	}
+	lines := strings.Split(result.Message, "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "OK ") || !strings.HasPrefix(lines[1], "CRITICAL ") {
+		t.Errorf("unexpected message: %q", result.Message)
+	}
+}
+
+func TestChecker_MetricsGenerator(t *testing.T) {
+	ts, caFile := newTLSServerWithCAFile(t)
+	conf := &config.CheckPlugin{Certificate: &config.CertificateCheck{
+		Endpoints: []string{ts.Listener.Addr().String()},
+		CAFile:    caFile,
+	}}
+	checker := &Checker{Name: "cert", Config: conf, Prober: NewProber("cert", conf, nil)}
+	g := checker.MetricsGenerator()
+
+	if values, _ := g.Generate(); len(values) != 0 {
+		t.Errorf("no values should be generated before checking: %v", values)
+	}
+	report := checker.Check()
+	if report.Status != StatusOK {
+		t.Errorf("status should be OK but %s: %s", report.Status, report.Message)
+	}
+	if values, _ := g.Generate(); len(values) != 1 {
+		t.Errorf("observed values should be generated: %v", values)
+	}
+	if values, _ := g.Generate(); len(values) != 0 {
+		t.Errorf("values should be generated only once: %v", values)
+	}
+
+	defs, err := g.PrepareGraphDefs()
+	if err != nil || len(defs) != 1 || defs[0].Name != "custom.certificate.#" {
+		t.Errorf("unexpected graph defs: %v, %v", defs, err)
+	}
+}
@@ -2,10 +2,12 @@ package checks
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/mackerelio/golib/logging"
 	"github.com/mackerelio/mackerel-agent/config"
+	"github.com/mackerelio/mackerel-agent/metrics"
 )
 
 var logger = logging.GetLogger("checks")
@@ -36,9 +38,15 @@ var exitCodeToStatus = map[int]Status{
 // Checker is the main interface of check monitoring.
 // It invokes its given command and transforms the result to a Report
 // to be sent to Mackerel periodically.
+// When Prober is set, it is used instead of the command.
 type Checker struct {
 	Name   string
 	Config *config.CheckPlugin
+	Prober Prober
+
+	mu        sync.Mutex
+	values    metrics.Values
+	checkedAt time.Time
 }
 
 // Report is what Checker produces by invoking its command.
@@ -53,11 +61,18 @@ type Report struct {
 }
 
 func (c *Checker) String() string {
+	if c.Prober != nil {
+		return fmt.Sprintf("checker %q prober=[%s]", c.Name, c.Prober)
+	}
 	return fmt.Sprintf("checker %q command=[%s]", c.Name, c.Config.Command)
 }
 
 // Check invokes the command and transforms its result to a Report.
 func (c *Checker) Check() *Report {
+	if c.Prober != nil {
+		return c.probe()
+	}
+
 	now := time.Now()
 	message, stderr, exitCode, err := c.Config.Command.Run()
 	if stderr != "" {
@@ -75,17 +90,62 @@ func (c *Checker) Check() *Report {
 		logger.Debugf("Checker %q status=%s message=%q", c.Name, status, message)
 	}
 
+	return c.newReport(status, message, now)
+}
+
+func (c *Checker) probe() *Report {
+	now := time.Now()
+	result := c.Prober.Probe()
+	logger.Debugf("Checker %q status=%s message=%q", c.Name, result.Status, result.Message)
+
+	if len(result.Values) > 0 {
+		c.mu.Lock()
+		if c.values == nil {
+			c.values = metrics.Values{}
+		}
+		for k, v := range result.Values {
+			c.values[k] = v
+		}
+		c.mu.Unlock()
+	}
+	return c.newReport(result.Status, result.Message, now)
+}
+
+func (c *Checker) newReport(status Status, message string, occurredAt time.Time) *Report {
+	c.mu.Lock()
+	c.checkedAt = time.Now()
+	c.mu.Unlock()
+
 	return &Report{
 		Name:                 c.Name,
 		Status:               status,
 		Message:              message,
-		OccurredAt:           now,
+		OccurredAt:           occurredAt,
 		NotificationInterval: c.Config.NotificationInterval,
 		MaxCheckAttempts:     c.Config.MaxCheckAttempts,
 		CustomIdentfier:      c.Config.CustomIdentifier,
 	}
 }
 
+// CheckedAt returns the time when the last check has finished.
+func (c *Checker) CheckedAt() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.checkedAt
+}
+
+// takeValues returns metric values observed by the Prober since the last call.
+func (c *Checker) takeValues() metrics.Values {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	values := c.values
+	c.values = nil
+	if values == nil {
+		return metrics.Values{}
+	}
+	return values
+}
+
 // Interval is the interval where the command is invoked.
 func (c *Checker) Interval() time.Duration {
 	if c.Config.CheckInterval != nil {
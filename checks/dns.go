@@ -0,0 +1,88 @@
+package checks
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/mackerelio/mackerel-agent/config"
+	"github.com/mackerelio/mackerel-agent/metrics"
+)
+
+// dnsProber checks that a name can be resolved.
+type dnsProber struct {
+	name    string
+	config  *config.DNSCheck
+	timeout time.Duration
+}
+
+func (p *dnsProber) String() string {
+	return fmt.Sprintf("dns name=%s server=%s", p.config.Name, p.config.Server)
+}
+
+// GraphDefs implements Prober.
+func (p *dnsProber) GraphDefs() map[string]metrics.CustomGraphDef {
+	return responseTimeGraphDefs
+}
+
+func (p *dnsProber) resolver() *net.Resolver {
+	if p.config.Server == "" {
+		return net.DefaultResolver
+	}
+	server := p.config.Server
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, server)
+		},
+	}
+}
+
+// Probe implements Prober.
+func (p *dnsProber) Probe() *ProbeResult {
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+
+	start := time.Now()
+	addrs, err := p.resolver().LookupHost(ctx, p.config.Name)
+	if err != nil {
+		return &ProbeResult{Status: StatusCritical, Message: err.Error()}
+	}
+	elapsed := time.Since(start)
+	values := responseTimeValues(p.name, elapsed)
+
+	var missing []string
+	for _, expected := range p.config.ExpectedAddresses {
+		if !containsAddress(addrs, expected) {
+			missing = append(missing, expected)
+		}
+	}
+	if len(missing) > 0 {
+		return &ProbeResult{
+			Status:  StatusCritical,
+			Message: fmt.Sprintf("%s resolved to %s, which does not contain %s", p.config.Name, strings.Join(addrs, ", "), strings.Join(missing, ", ")),
+			Values:  values,
+		}
+	}
+	return &ProbeResult{
+		Status:  StatusOK,
+		Message: fmt.Sprintf("%s resolved to %s in %s", p.config.Name, strings.Join(addrs, ", "), elapsed.Round(time.Millisecond)),
+		Values:  values,
+	}
+}
+
+func containsAddress(addrs []string, addr string) bool {
+	ip := net.ParseIP(addr)
+	for _, a := range addrs {
+		if a == addr || (ip != nil && ip.Equal(net.ParseIP(a))) {
+			return true
+		}
+	}
+	return false
+}
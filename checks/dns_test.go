@@ -0,0 +1,42 @@
+package checks
+
+import (
+	"testing"
+
+	"github.com/mackerelio/mackerel-agent/config"
+)
+
+func TestDNSProber(t *testing.T) {
+	tests := []struct {
+		name   string
+		config config.DNSCheck
+		status Status
+	}{
+		{
+			name:   "ok",
+			config: config.DNSCheck{Name: "localhost"},
+			status: StatusOK,
+		},
+		{
+			name:   "expected addresses",
+			config: config.DNSCheck{Name: "localhost", ExpectedAddresses: []string{"127.0.0.1"}},
+			status: StatusOK,
+		},
+		{
+			name:   "unexpected addresses",
+			config: config.DNSCheck{Name: "localhost", ExpectedAddresses: []string{"192.0.2.1"}},
+			status: StatusCritical,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := NewProber("resolve", &config.CheckPlugin{DNS: &tt.config}, nil).Probe()
+			if result.Status != tt.status {
+				t.Errorf("status should be %s but %s: %s", tt.status, result.Status, result.Message)
+			}
+			if _, ok := result.Values["custom.probe.response_time.resolve"]; !ok {
+				t.Errorf("response time should be reported: %v", result.Values)
+			}
+		})
+	}
+}
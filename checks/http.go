@@ -0,0 +1,90 @@
+package checks
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/mackerelio/mackerel-agent/config"
+	"github.com/mackerelio/mackerel-agent/metrics"
+)
+
+// httpProber checks the status and the body of an HTTP response.
+type httpProber struct {
+	name    string
+	config  *config.HTTPCheck
+	timeout time.Duration
+}
+
+// The body beyond this size is not matched with expected_body.
+const httpProbeBodyLimit = 1024 * 1024
+
+func (p *httpProber) String() string {
+	return fmt.Sprintf("http url=%s", p.config.URL)
+}
+
+// GraphDefs implements Prober.
+func (p *httpProber) GraphDefs() map[string]metrics.CustomGraphDef {
+	return responseTimeGraphDefs
+}
+
+// Probe implements Prober.
+func (p *httpProber) Probe() *ProbeResult {
+	method := p.config.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	req, err := http.NewRequest(method, p.config.URL, nil)
+	if err != nil {
+		return &ProbeResult{Status: StatusUnknown, Message: err.Error()}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if p.config.SkipVerify {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true} // nolint:gosec
+	}
+	client := &http.Client{Transport: transport, Timeout: p.timeout}
+	defer client.CloseIdleConnections()
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return &ProbeResult{Status: StatusCritical, Message: err.Error()}
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, httpProbeBodyLimit))
+	if err != nil {
+		return &ProbeResult{Status: StatusCritical, Message: err.Error()}
+	}
+	elapsed := time.Since(start)
+	values := responseTimeValues(p.name, elapsed)
+
+	if !p.isExpectedStatus(resp.StatusCode) {
+		return &ProbeResult{
+			Status:  StatusCritical,
+			Message: fmt.Sprintf("unexpected status: %s", resp.Status),
+			Values:  values,
+		}
+	}
+	if re := p.config.ExpectedBody.Regexp; re != nil && !re.Match(body) {
+		return &ProbeResult{
+			Status:  StatusCritical,
+			Message: fmt.Sprintf("response body does not match %q", re),
+			Values:  values,
+		}
+	}
+	return &ProbeResult{
+		Status:  StatusOK,
+		Message: fmt.Sprintf("%s in %s", resp.Status, elapsed.Round(time.Millisecond)),
+		Values:  values,
+	}
+}
+
+func (p *httpProber) isExpectedStatus(code int) bool {
+	if p.config.ExpectedStatus != 0 {
+		return code == p.config.ExpectedStatus
+	}
+	return 200 <= code && code < 300
+}
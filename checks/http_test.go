@@ -0,0 +1,84 @@
+package checks
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/mackerelio/mackerel-agent/config"
+)
+
+func TestHTTPProber(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/healthz":
+			w.Write([]byte("ok\n"))
+		case "/slow":
+			time.Sleep(500 * time.Millisecond)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(ts.Close)
+
+	tests := []struct {
+		name    string
+		config  config.HTTPCheck
+		timeout int64
+		status  Status
+		values  bool
+	}{
+		{
+			name:   "ok",
+			config: config.HTTPCheck{URL: ts.URL + "/healthz"},
+			status: StatusOK,
+			values: true,
+		},
+		{
+			name:   "expected body",
+			config: config.HTTPCheck{URL: ts.URL + "/healthz", ExpectedBody: config.Regexpwrapper{Regexp: regexp.MustCompile(`^ok\n`)}},
+			status: StatusOK,
+			values: true,
+		},
+		{
+			name:   "unexpected body",
+			config: config.HTTPCheck{URL: ts.URL + "/healthz", ExpectedBody: config.Regexpwrapper{Regexp: regexp.MustCompile(`^ng$`)}},
+			status: StatusCritical,
+			values: true,
+		},
+		{
+			name:   "unexpected status",
+			config: config.HTTPCheck{URL: ts.URL + "/missing"},
+			status: StatusCritical,
+			values: true,
+		},
+		{
+			name:   "expected status",
+			config: config.HTTPCheck{URL: ts.URL + "/missing", ExpectedStatus: http.StatusNotFound},
+			status: StatusOK,
+			values: true,
+		},
+		{
+			name:    "timeout",
+			config:  config.HTTPCheck{URL: ts.URL + "/slow"},
+			timeout: 100 * int64(time.Millisecond),
+			status:  StatusCritical,
+			values:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := &config.CheckPlugin{HTTP: &tt.config}
+			conf.Command.TimeoutDuration = time.Duration(tt.timeout)
+			result := NewProber("web", conf, nil).Probe()
+			if result.Status != tt.status {
+				t.Errorf("status should be %s but %s: %s", tt.status, result.Status, result.Message)
+			}
+			if _, ok := result.Values["custom.probe.response_time.web"]; ok != tt.values {
+				t.Errorf("unexpected values: %v", result.Values)
+			}
+		})
+	}
+}
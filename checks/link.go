@@ -0,0 +1,97 @@
+package checks
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mackerelio/mackerel-agent/config"
+	"github.com/mackerelio/mackerel-agent/metrics"
+	"github.com/mackerelio/mackerel-agent/util"
+)
+
+// linkProber checks link states of network interfaces and bonding slaves.
+// A bond is CRITICAL if it is down, and WARNING if any of the slaves is down.
+type linkProber struct {
+	name           string
+	config         *config.LinkCheck
+	readLinkStatus func(name string) (*util.LinkStatus, error)
+	readBonds      func() ([]*util.Bond, error)
+}
+
+func (p *linkProber) String() string {
+	return fmt.Sprintf("link interfaces=%s bonds=%s", strings.Join(p.config.Interfaces, ","), strings.Join(p.config.Bonds, ","))
+}
+
+// GraphDefs implements Prober.
+func (p *linkProber) GraphDefs() map[string]metrics.CustomGraphDef {
+	return nil
+}
+
+// Probe implements Prober.
+func (p *linkProber) Probe() *ProbeResult {
+	bonds, err := p.readBonds()
+	if err != nil {
+		return &ProbeResult{Status: StatusUnknown, Message: err.Error()}
+	}
+	checkAll := len(p.config.Interfaces) == 0 && len(p.config.Bonds) == 0
+	if checkAll && len(bonds) == 0 {
+		return &ProbeResult{Status: StatusUnknown, Message: "no bonding interfaces found"}
+	}
+
+	status := StatusOK
+	var problems, healthy []string
+	report := func(s Status, format string, args ...any) {
+		status = worseStatus(status, s)
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	for _, name := range p.config.Interfaces {
+		link, err := p.readLinkStatus(name)
+		switch {
+		case err != nil:
+			report(StatusCritical, "%s not found", name)
+		case link.OperState != "up":
+			report(StatusCritical, "%s is %s", name, link.OperState)
+		default:
+			healthy = append(healthy, name)
+		}
+	}
+
+	found := make(map[string]*util.Bond)
+	for _, b := range bonds {
+		found[b.Name] = b
+	}
+	names := p.config.Bonds
+	if checkAll {
+		for _, b := range bonds {
+			names = append(names, b.Name)
+		}
+	}
+	for _, name := range names {
+		b, ok := found[name]
+		if !ok {
+			report(StatusCritical, "%s not found", name)
+			continue
+		}
+		if b.MIIStatus != "up" {
+			report(StatusCritical, "%s is %s", name, b.MIIStatus)
+			continue
+		}
+		var down []string
+		for _, slave := range b.Slaves {
+			if slave.MIIStatus != "up" {
+				down = append(down, slave.Name)
+			}
+		}
+		if len(down) > 0 {
+			report(StatusWarning, "%s slave %s down (%d/%d up)", name, strings.Join(down, ","), len(b.Slaves)-len(down), len(b.Slaves))
+			continue
+		}
+		healthy = append(healthy, name)
+	}
+
+	if len(problems) > 0 {
+		return &ProbeResult{Status: status, Message: strings.Join(problems, "; ")}
+	}
+	return &ProbeResult{Status: StatusOK, Message: strings.Join(healthy, ", ") + " up"}
+}
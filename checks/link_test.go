@@ -0,0 +1,97 @@
+package checks
+
+import (
+	"os"
+	"testing"
+
+	"github.com/mackerelio/mackerel-agent/config"
+	"github.com/mackerelio/mackerel-agent/util"
+)
+
+// fakeLinks returns what util.ReadLinkStatus and util.ReadBonds read from
+// util/testdata/net and util/testdata/bonding.
+func fakeLinks() (func(string) (*util.LinkStatus, error), func() ([]*util.Bond, error)) {
+	links := map[string]*util.LinkStatus{
+		"eth0": {OperState: "up", MTU: 9000, Speed: 1000, Duplex: "full"},
+		"eth1": {OperState: "down", MTU: 1500},
+	}
+	bonds := []*util.Bond{
+		{Name: "bond0", Mode: "fault-tolerance (active-backup)", MIIStatus: "up", Slaves: []util.BondSlave{
+			{Name: "eth0", MIIStatus: "up", Speed: "1000 Mbps", Duplex: "full"},
+			{Name: "eth1", MIIStatus: "down", Speed: "Unknown", Duplex: "Unknown", LinkFailureCount: 3},
+		}},
+		{Name: "bond1", Mode: "IEEE 802.3ad Dynamic link aggregation", MIIStatus: "up", Slaves: []util.BondSlave{
+			{Name: "eth2", MIIStatus: "up", Speed: "10000 Mbps", Duplex: "full"},
+			{Name: "eth3", MIIStatus: "up", Speed: "10000 Mbps", Duplex: "full", LinkFailureCount: 1},
+		}},
+	}
+	readLinkStatus := func(name string) (*util.LinkStatus, error) {
+		if link, ok := links[name]; ok {
+			return link, nil
+		}
+		return nil, os.ErrNotExist
+	}
+	return readLinkStatus, func() ([]*util.Bond, error) { return bonds, nil }
+}
+
+func TestLinkProber(t *testing.T) {
+	tests := []struct {
+		name   string
+		config config.LinkCheck
+		status Status
+		msg    string
+	}{
+		{
+			name:   "healthy",
+			config: config.LinkCheck{Interfaces: []string{"eth0"}, Bonds: []string{"bond1"}},
+			status: StatusOK,
+			msg:    "eth0, bond1 up",
+		},
+		{
+			name:   "slave down",
+			config: config.LinkCheck{Bonds: []string{"bond0", "bond1"}},
+			status: StatusWarning,
+			msg:    "bond0 slave eth1 down (1/2 up)",
+		},
+		{
+			name:   "interface down",
+			config: config.LinkCheck{Interfaces: []string{"eth1"}, Bonds: []string{"bond0"}},
+			status: StatusCritical,
+			msg:    "eth1 is down; bond0 slave eth1 down (1/2 up)",
+		},
+		{
+			name:   "missing",
+			config: config.LinkCheck{Interfaces: []string{"eth9"}, Bonds: []string{"bond9"}},
+			status: StatusCritical,
+			msg:    "eth9 not found; bond9 not found",
+		},
+		{
+			name:   "all",
+			config: config.LinkCheck{},
+			status: StatusWarning,
+			msg:    "bond0 slave eth1 down (1/2 up)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewProber("link", &config.CheckPlugin{Link: &tt.config}, nil).(*linkProber)
+			p.readLinkStatus, p.readBonds = fakeLinks()
+			result := p.Probe()
+			if result.Status != tt.status {
+				t.Errorf("status should be %s but %s: %s", tt.status, result.Status, result.Message)
+			}
+			if result.Message != tt.msg {
+				t.Errorf("message should be %q but %q", tt.msg, result.Message)
+			}
+		})
+	}
+}
+
+func TestLinkProber_noBonds(t *testing.T) {
+	p := NewProber("link", &config.CheckPlugin{Link: &config.LinkCheck{}}, nil).(*linkProber)
+	// util.ReadBonds returns no bonds without the bonding driver.
+	p.readBonds = func() ([]*util.Bond, error) { return nil, nil }
+	if result := p.Probe(); result.Status != StatusUnknown {
+		t.Errorf("status should be UNKNOWN but %s: %s", result.Status, result.Message)
+	}
+}
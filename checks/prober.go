@@ -0,0 +1,134 @@
+package checks
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mackerelio/mackerel-agent/config"
+	"github.com/mackerelio/mackerel-agent/metrics"
+	"github.com/mackerelio/mackerel-agent/util"
+	mkr "github.com/mackerelio/mackerel-client-go"
+)
+
+// Prober is a check which is performed by the agent itself
+// instead of invoking an external command.
+type Prober interface {
+	fmt.Stringer
+	Probe() *ProbeResult
+	// GraphDefs returns graph definitions of the metric values in ProbeResult.
+	GraphDefs() map[string]metrics.CustomGraphDef
+}
+
+// ProbeResult is what Prober produces.
+// Values are optional metric values observed while probing,
+// which are posted as custom metrics.
+type ProbeResult struct {
+	Status  Status
+	Message string
+	Values  metrics.Values
+}
+
+const defaultProbeTimeout = 10 * time.Second
+
+// NewProber returns the built-in Prober configured in conf.
+// It returns nil if conf is a check plugin invoking a command.
+// stats may be nil, and then the probers referring to it regard that
+// the agent has observed nothing.
+func NewProber(name string, conf *config.CheckPlugin, stats *metrics.AgentStats) Prober {
+	timeout := conf.Command.TimeoutDuration
+	if timeout <= 0 {
+		timeout = defaultProbeTimeout
+	}
+	switch {
+	case conf.Certificate != nil:
+		return &certificateProber{name: name, config: conf.Certificate, timeout: timeout}
+	case conf.TCP != nil:
+		return &tcpProber{name: name, config: conf.TCP, timeout: timeout}
+	case conf.HTTP != nil:
+		return &httpProber{name: name, config: conf.HTTP, timeout: timeout}
+	case conf.DNS != nil:
+		return &dnsProber{name: name, config: conf.DNS, timeout: timeout}
+	case conf.RAID != nil:
+		return &raidProber{
+			name:   name,
+			config: conf.RAID,
+			readMdstat: func() ([]*util.MdArray, error) {
+				return util.ReadMdstat(util.MdstatPath)
+			},
+			readZpoolStates: func() (map[string]string, error) {
+				return util.ReadZpoolStates(util.ZFSKstatPath)
+			},
+		}
+	case conf.Link != nil:
+		return &linkProber{
+			name:   name,
+			config: conf.Link,
+			readLinkStatus: func(name string) (*util.LinkStatus, error) {
+				return util.ReadLinkStatus(util.SysClassNetPath, name)
+			},
+			readBonds: func() ([]*util.Bond, error) {
+				return util.ReadBonds(util.BondingPath)
+			},
+		}
+	case conf.TimeSync != nil:
+		return &timeSyncProber{name: name, config: conf.TimeSync, stats: stats, readTimeSync: util.ReadTimeSync}
+	}
+	return nil
+}
+
+var statusSeverities = map[Status]int{
+	StatusOK:       0,
+	StatusUnknown:  1,
+	StatusWarning:  2,
+	StatusCritical: 3,
+}
+
+// worseStatus returns the more severe one of s1 and s2.
+func worseStatus(s1, s2 Status) Status {
+	if statusSeverities[s2] > statusSeverities[s1] {
+		return s2
+	}
+	return s1
+}
+
+// MetricsGenerator returns a generator which emits the metric values
+// observed by the Prober of c. It returns nil if c does not have a Prober.
+func (c *Checker) MetricsGenerator() metrics.PluginGenerator {
+	if c.Prober == nil {
+		return nil
+	}
+	return &probeGenerator{checker: c}
+}
+
+type probeGenerator struct {
+	checker *Checker
+}
+
+func (g *probeGenerator) Generate() (metrics.Values, error) {
+	return g.checker.takeValues(), nil
+}
+
+func (g *probeGenerator) PrepareGraphDefs() ([]*mkr.GraphDefsParam, error) {
+	return metrics.NewGraphDefsParam(g.checker.Prober.GraphDefs()), nil
+}
+
+func (g *probeGenerator) CustomIdentifier() *string {
+	return g.checker.Config.CustomIdentifier
+}
+
+var responseTimeGraphDefs = map[string]metrics.CustomGraphDef{
+	"probe.response_time": {
+		Label: "Probe Response Time",
+		Unit:  "milliseconds",
+		Metrics: []metrics.CustomGraphMetricDef{
+			{Name: "*", Label: "%1"},
+		},
+	},
+}
+
+// responseTimeValues returns `custom.probe.response_time.{check}` in milliseconds.
+func responseTimeValues(name string, d time.Duration) metrics.Values {
+	return metrics.Values{
+		"custom.probe.response_time." + util.SanitizeMetricKey(name): float64(d) / float64(time.Millisecond),
+	}
+}
@@ -0,0 +1,106 @@
+package checks
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mackerelio/mackerel-agent/config"
+	"github.com/mackerelio/mackerel-agent/metrics"
+	"github.com/mackerelio/mackerel-agent/util"
+)
+
+// raidProber checks the health of Linux software RAID (md) arrays and ZFS pools.
+type raidProber struct {
+	name            string
+	config          *config.RAIDCheck
+	readMdstat      func() ([]*util.MdArray, error)
+	readZpoolStates func() (map[string]string, error)
+}
+
+func (p *raidProber) String() string {
+	return fmt.Sprintf("raid arrays=%s pools=%s", strings.Join(p.config.Arrays, ","), strings.Join(p.config.Pools, ","))
+}
+
+// GraphDefs implements Prober.
+// The health of arrays and pools is posted by the [raid] custom metrics instead.
+func (p *raidProber) GraphDefs() map[string]metrics.CustomGraphDef {
+	return nil
+}
+
+// Probe implements Prober.
+func (p *raidProber) Probe() *ProbeResult {
+	arrays, err := p.readMdstat()
+	if err != nil {
+		return &ProbeResult{Status: StatusUnknown, Message: err.Error()}
+	}
+	pools, err := p.readZpoolStates()
+	if err != nil {
+		return &ProbeResult{Status: StatusUnknown, Message: err.Error()}
+	}
+
+	found := make(map[string]*util.MdArray)
+	for _, a := range arrays {
+		found[a.Name] = a
+	}
+	names := p.config.Arrays
+	checkAll := len(p.config.Arrays) == 0 && len(p.config.Pools) == 0
+	if checkAll {
+		for _, a := range arrays {
+			// The containers of external metadata such as IMSM and DDF are
+			// always inactive, so inactive arrays are checked only if named.
+			if a.State != "inactive" {
+				names = append(names, a.Name)
+			}
+		}
+		if len(names) == 0 && len(pools) == 0 {
+			return &ProbeResult{Status: StatusUnknown, Message: "no md arrays or ZFS pools found"}
+		}
+	}
+
+	var problems, healthy []string
+	fail := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+	for _, name := range names {
+		a, ok := found[name]
+		switch {
+		case !ok:
+			fail("%s not found", name)
+		case a.State == "inactive":
+			fail("%s is inactive", name)
+		case a.Degraded():
+			msg := fmt.Sprintf("%s is degraded [%d/%d]", name, a.Disks, a.ActiveDisks)
+			if a.SyncAction != "" {
+				msg += fmt.Sprintf(", %s %.1f%%", a.SyncAction, a.SyncPercent)
+			}
+			problems = append(problems, msg)
+		default:
+			healthy = append(healthy, name)
+		}
+	}
+
+	poolNames := p.config.Pools
+	if checkAll {
+		for name := range pools {
+			poolNames = append(poolNames, name)
+		}
+		sort.Strings(poolNames)
+	}
+	for _, name := range poolNames {
+		state, ok := pools[name]
+		switch {
+		case !ok:
+			fail("pool %s not found", name)
+		case state != "ONLINE":
+			fail("pool %s is %s", name, state)
+		default:
+			healthy = append(healthy, name)
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ProbeResult{Status: StatusCritical, Message: strings.Join(problems, "; ")}
+	}
+	return &ProbeResult{Status: StatusOK, Message: strings.Join(healthy, ", ") + " healthy"}
+}
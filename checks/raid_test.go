@@ -0,0 +1,101 @@
+package checks
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mackerelio/mackerel-agent/config"
+	"github.com/mackerelio/mackerel-agent/util"
+)
+
+// fakeRAID returns what util.ReadMdstat and util.ReadZpoolStates read
+// from util/testdata/mdstat and util/testdata/zfs.
+func fakeRAID() ([]*util.MdArray, map[string]string) {
+	arrays := []*util.MdArray{
+		{Name: "md2", State: "active", Level: "raid0"},
+		{Name: "md1", State: "active", Level: "raid5", Disks: 3, ActiveDisks: 2, FailedDisks: 1, SyncAction: "recovery", SyncPercent: 8.5},
+		{Name: "md0", State: "active", Level: "raid1", Disks: 2, ActiveDisks: 2, SyncAction: "check", SyncPercent: 52.3},
+		{Name: "md127", State: "inactive"},
+	}
+	pools := map[string]string{"tank": "ONLINE", "backup": "DEGRADED"}
+	return arrays, pools
+}
+
+func TestRAIDProber(t *testing.T) {
+	tests := []struct {
+		name   string
+		config config.RAIDCheck
+		status Status
+		msg    string
+	}{
+		{
+			name:   "healthy",
+			config: config.RAIDCheck{Arrays: []string{"md0", "md2"}, Pools: []string{"tank"}},
+			status: StatusOK,
+			msg:    "md0, md2, tank healthy",
+		},
+		{
+			name:   "degraded array",
+			config: config.RAIDCheck{Arrays: []string{"md0", "md1"}},
+			status: StatusCritical,
+			msg:    "md1 is degraded [3/2], recovery 8.5%",
+		},
+		{
+			name:   "degraded pool",
+			config: config.RAIDCheck{Pools: []string{"backup"}},
+			status: StatusCritical,
+			msg:    "pool backup is DEGRADED",
+		},
+		{
+			name:   "missing",
+			config: config.RAIDCheck{Arrays: []string{"md9"}, Pools: []string{"rpool"}},
+			status: StatusCritical,
+			msg:    "md9 not found; pool rpool not found",
+		},
+		{
+			name:   "all",
+			config: config.RAIDCheck{},
+			status: StatusCritical,
+			msg:    "md1 is degraded [3/2], recovery 8.5%; pool backup is DEGRADED",
+		},
+		{
+			name:   "inactive",
+			config: config.RAIDCheck{Arrays: []string{"md0", "md127"}},
+			status: StatusCritical,
+			msg:    "md127 is inactive",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			arrays, pools := fakeRAID()
+			p := NewProber("raid", &config.CheckPlugin{RAID: &tt.config}, nil).(*raidProber)
+			p.readMdstat = func() ([]*util.MdArray, error) { return arrays, nil }
+			p.readZpoolStates = func() (map[string]string, error) { return pools, nil }
+			result := p.Probe()
+			if result.Status != tt.status {
+				t.Errorf("status should be %s but %s: %s", tt.status, result.Status, result.Message)
+			}
+			if result.Message != tt.msg {
+				t.Errorf("message should be %q but %q", tt.msg, result.Message)
+			}
+		})
+	}
+}
+
+func TestRAIDProber_notFound(t *testing.T) {
+	p := NewProber("raid", &config.CheckPlugin{RAID: &config.RAIDCheck{}}, nil).(*raidProber)
+	// util.ReadMdstat and util.ReadZpoolStates return nothing without md and ZFS.
+	p.readMdstat = func() ([]*util.MdArray, error) { return nil, nil }
+	p.readZpoolStates = func() (map[string]string, error) { return nil, nil }
+	result := p.Probe()
+	if result.Status != StatusUnknown || !strings.Contains(result.Message, "no md arrays") {
+		t.Errorf("status should be UNKNOWN but %s: %s", result.Status, result.Message)
+	}
+
+	// Only the container of external metadata
+	p.readMdstat = func() ([]*util.MdArray, error) { return []*util.MdArray{{Name: "md127", State: "inactive"}}, nil }
+	result = p.Probe()
+	if result.Status != StatusUnknown || !strings.Contains(result.Message, "no md arrays") {
+		t.Errorf("status should be UNKNOWN but %s: %s", result.Status, result.Message)
+	}
+}
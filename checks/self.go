@@ -0,0 +1,146 @@
+package checks
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mackerelio/mackerel-agent/config"
+	"github.com/mackerelio/mackerel-agent/metrics"
+)
+
+// SelfCheckName is the name of the check monitoring the agent itself.
+const SelfCheckName = "mackerel-agent"
+
+const (
+	defaultSelfCheckPostQueueGrowthMinutes = 10
+	defaultSelfCheckPluginFailureCount     = 5
+
+	// commands without timeout_seconds are killed after 30 seconds by cmdutil.
+	defaultCheckTimeout = 30 * time.Second
+)
+
+// selfProber inspects AgentStats and the other checkers.
+type selfProber struct {
+	config    *config.SelfCheck
+	stats     *metrics.AgentStats
+	checkers  []*Checker
+	startedAt time.Time
+
+	// the length of the post queue at the last probe, and the time when
+	// the queue started growing without draining since then.
+	postQueueLength       int
+	postQueueGrowingSince time.Time
+}
+
+// NewSelfProber returns a Prober which reports the health of the agent itself.
+func NewSelfProber(conf *config.SelfCheck, stats *metrics.AgentStats, checkers []*Checker) Prober {
+	return &selfProber{
+		config:    conf,
+		stats:     stats,
+		checkers:  checkers,
+		startedAt: time.Now(),
+	}
+}
+
+func (p *selfProber) String() string {
+	return "self"
+}
+
+// GraphDefs implements Prober.
+func (p *selfProber) GraphDefs() map[string]metrics.CustomGraphDef {
+	return nil
+}
+
+// Probe implements Prober.
+func (p *selfProber) Probe() *ProbeResult {
+	return p.probe(time.Now())
+}
+
+func (p *selfProber) probe(now time.Time) *ProbeResult {
+	status := StatusOK
+	var messages []string
+	report := func(s Status, format string, args ...any) {
+		status = worseStatus(status, s)
+		messages = append(messages, fmt.Sprintf(format, args...))
+	}
+
+	growthMinutes := p.config.PostQueueGrowthMinutes
+	if growthMinutes <= 0 {
+		growthMinutes = defaultSelfCheckPostQueueGrowthMinutes
+	}
+	if since := p.observePostQueue(now); !since.IsZero() {
+		if d := now.Sub(since); d >= time.Duration(growthMinutes)*time.Minute {
+			msg := fmt.Sprintf("post queue has been growing for %s (%d values)", d.Round(time.Second), p.postQueueLength)
+			if failing := p.stats.PostFailingSince(); !failing.IsZero() {
+				msg += fmt.Sprintf(", posting metrics has been failing for %s", now.Sub(failing).Round(time.Second))
+			}
+			report(StatusCritical, "%s", msg)
+		}
+	}
+
+	if err := p.stats.GraphDefsError(); err != nil {
+		report(StatusWarning, "failed to create graph definitions: %s", err)
+	}
+
+	pluginFailureCount := p.config.PluginFailureCount
+	if pluginFailureCount <= 0 {
+		pluginFailureCount = defaultSelfCheckPluginFailureCount
+	}
+	failures := p.stats.PluginFailures()
+	plugins := make([]string, 0, len(failures))
+	for plugin := range failures {
+		plugins = append(plugins, plugin)
+	}
+	sort.Strings(plugins)
+	for _, plugin := range plugins {
+		if n := failures[plugin]; n >= pluginFailureCount {
+			report(StatusWarning, "plugin %q has failed %d times in a row", plugin, n)
+		}
+	}
+
+	for _, c := range p.checkers {
+		checkedAt := c.CheckedAt()
+		if checkedAt.IsZero() {
+			checkedAt = p.startedAt
+		}
+		if d := now.Sub(checkedAt); d > c.stuckThreshold() {
+			report(StatusWarning, "check %q has not completed for %s", c.Name, d.Round(time.Second))
+		}
+	}
+
+	if len(messages) == 0 {
+		return &ProbeResult{Status: StatusOK, Message: "mackerel-agent is working"}
+	}
+	return &ProbeResult{Status: status, Message: strings.Join(messages, "\n")}
+}
+
+// observePostQueue samples the length of the post queue and returns the time
+// when the queue started growing. A queue which stays at the same non-zero
+// length is considered to be still growing since it has not drained, while
+// one which has shrunk or emptied is not.
+func (p *selfProber) observePostQueue(now time.Time) time.Time {
+	n := p.stats.PostQueueLength()
+	switch {
+	case n == 0 || n < p.postQueueLength:
+		p.postQueueGrowingSince = time.Time{}
+	case n > p.postQueueLength && p.postQueueGrowingSince.IsZero():
+		p.postQueueGrowingSince = now
+	}
+	p.postQueueLength = n
+	return p.postQueueGrowingSince
+}
+
+// stuckThreshold returns the duration after which c is considered to be stuck
+// if it has not completed any check.
+func (c *Checker) stuckThreshold() time.Duration {
+	timeout := c.Config.Command.TimeoutDuration
+	if timeout <= 0 {
+		timeout = defaultCheckTimeout
+		if c.Prober != nil {
+			timeout = defaultProbeTimeout
+		}
+	}
+	return 2*c.Interval() + timeout
+}
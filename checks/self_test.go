@@ -0,0 +1,99 @@
+package checks
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mackerelio/mackerel-agent/config"
+	"github.com/mackerelio/mackerel-agent/metrics"
+)
+
+func TestSelfProber(t *testing.T) {
+	stats := &metrics.AgentStats{}
+	queueLength := 0
+	stats.SetPostQueueLengthFunc(func() int { return queueLength })
+	checker := &Checker{
+		Name:   "heartbeat",
+		Config: &config.CheckPlugin{Command: config.Command{Cmd: "go run testdata/exit.go -code 0 -message OK"}},
+	}
+	conf := &config.SelfCheck{Enabled: true, PluginFailureCount: 2}
+	p := NewSelfProber(conf, stats, []*Checker{checker}).(*selfProber)
+	now := time.Now()
+
+	result := p.probe(now)
+	if result.Status != StatusOK {
+		t.Errorf("status should be OK but %s: %s", result.Status, result.Message)
+	}
+
+	stats.RecordPluginExecution("plugin1", time.Second, 1, nil)
+	stats.RecordPluginExecution("plugin1", time.Second, 1, nil)
+	stats.RecordGraphDefs(errors.New("bad request"))
+	stats.RecordPost(errors.New("network error"))
+	queueLength = 1
+	result = p.probe(now.Add(time.Minute))
+	if result.Status != StatusWarning {
+		t.Errorf("status should be WARNING but %s: %s", result.Status, result.Message)
+	}
+	if !strings.Contains(result.Message, `plugin "plugin1" has failed 2 times in a row`) {
+		t.Errorf("message should contain the plugin failure: %q", result.Message)
+	}
+	if !strings.Contains(result.Message, "failed to create graph definitions: bad request") {
+		t.Errorf("message should contain the graph definitions failure: %q", result.Message)
+	}
+
+	queueLength = 60
+	result = p.probe(now.Add(time.Hour))
+	if result.Status != StatusCritical {
+		t.Errorf("status should be CRITICAL but %s: %s", result.Status, result.Message)
+	}
+	if !strings.Contains(result.Message, "post queue has been growing for 59m0s (60 values), posting metrics has been failing for") {
+		t.Errorf("message should contain the post queue growth: %q", result.Message)
+	}
+	if !strings.Contains(result.Message, `check "heartbeat" has not completed for`) {
+		t.Errorf("message should contain the stuck check: %q", result.Message)
+	}
+
+	stats.RecordPluginExecution("plugin1", time.Second, 0, nil)
+	stats.RecordGraphDefs(nil)
+	stats.RecordPost(nil)
+	queueLength = 0
+	checker.Check()
+	result = p.probe(time.Now())
+	if result.Status != StatusOK {
+		t.Errorf("status should be recovered to OK but %s: %s", result.Status, result.Message)
+	}
+}
+
+func TestSelfProber_PostQueueGrowthMinutes(t *testing.T) {
+	stats := &metrics.AgentStats{}
+	queueLength := 0
+	stats.SetPostQueueLengthFunc(func() int { return queueLength })
+	p := NewSelfProber(&config.SelfCheck{Enabled: true, PostQueueGrowthMinutes: 30}, stats, nil).(*selfProber)
+	now := time.Now()
+
+	testCases := []struct {
+		minutes     int
+		queueLength int
+		status      Status
+	}{
+		{0, 0, StatusOK},
+		{1, 1, StatusOK},
+		{20, 20, StatusOK},
+		{31, 31, StatusCritical},
+		{32, 31, StatusCritical}, // not drained
+		{33, 5, StatusOK},        // draining
+		{34, 6, StatusOK},        // growing again since 34 minutes
+		{63, 7, StatusOK},
+		{64, 8, StatusCritical},
+		{65, 0, StatusOK},
+	}
+	for _, tc := range testCases {
+		queueLength = tc.queueLength
+		result := p.probe(now.Add(time.Duration(tc.minutes) * time.Minute))
+		if result.Status != tc.status {
+			t.Errorf("status at %d minutes with %d values should be %s but %s: %s", tc.minutes, tc.queueLength, tc.status, result.Status, result.Message)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package checks
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/mackerelio/mackerel-agent/config"
+	"github.com/mackerelio/mackerel-agent/metrics"
+)
+
+// tcpProber checks that a TCP port accepts connections.
+type tcpProber struct {
+	name    string
+	config  *config.TCPCheck
+	timeout time.Duration
+}
+
+func (p *tcpProber) String() string {
+	return fmt.Sprintf("tcp address=%s", p.config.Address)
+}
+
+// GraphDefs implements Prober.
+func (p *tcpProber) GraphDefs() map[string]metrics.CustomGraphDef {
+	return responseTimeGraphDefs
+}
+
+// Probe implements Prober.
+func (p *tcpProber) Probe() *ProbeResult {
+	start := time.Now()
+	conn, err := net.DialTimeout("tcp", p.config.Address, p.timeout)
+	if err != nil {
+		return &ProbeResult{Status: StatusCritical, Message: err.Error()}
+	}
+	elapsed := time.Since(start)
+	conn.Close()
+
+	return &ProbeResult{
+		Status:  StatusOK,
+		Message: fmt.Sprintf("connected to %s in %s", p.config.Address, elapsed.Round(time.Millisecond)),
+		Values:  responseTimeValues(p.name, elapsed),
+	}
+}
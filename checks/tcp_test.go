@@ -0,0 +1,34 @@
+package checks
+
+import (
+	"net"
+	"testing"
+
+	"github.com/mackerelio/mackerel-agent/config"
+)
+
+func TestTCPProber(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+
+	p := NewProber("port", &config.CheckPlugin{TCP: &config.TCPCheck{Address: addr}}, nil)
+	result := p.Probe()
+	if result.Status != StatusOK {
+		t.Errorf("status should be OK but %s: %s", result.Status, result.Message)
+	}
+	if _, ok := result.Values["custom.probe.response_time.port"]; !ok {
+		t.Errorf("response time should be reported: %v", result.Values)
+	}
+
+	ln.Close()
+	result = p.Probe()
+	if result.Status != StatusCritical {
+		t.Errorf("status should be CRITICAL but %s: %s", result.Status, result.Message)
+	}
+	if len(result.Values) != 0 {
+		t.Errorf("response time should not be reported: %v", result.Values)
+	}
+}
@@ -0,0 +1,90 @@
+package checks
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/mackerelio/mackerel-agent/config"
+	"github.com/mackerelio/mackerel-agent/metrics"
+	"github.com/mackerelio/mackerel-agent/util"
+)
+
+// The offset from the time of Mackerel API is accurate only to about
+// one second, so the thresholds should be longer than that.
+const (
+	defaultTimeSyncWarningSeconds  = 3
+	defaultTimeSyncCriticalSeconds = 10
+)
+
+// timeSyncProber checks that the system clock is synchronized and
+// its offset from the time of Mackerel API is within the thresholds.
+type timeSyncProber struct {
+	name         string
+	config       *config.TimeSyncCheck
+	stats        *metrics.AgentStats
+	readTimeSync func() (*util.TimeSync, error)
+}
+
+func (p *timeSyncProber) String() string {
+	warning, critical := p.thresholds()
+	return fmt.Sprintf("timesync warning=%s critical=%s", warning, critical)
+}
+
+// GraphDefs implements Prober.
+func (p *timeSyncProber) GraphDefs() map[string]metrics.CustomGraphDef {
+	return nil
+}
+
+func (p *timeSyncProber) thresholds() (warning, critical time.Duration) {
+	warningSeconds, criticalSeconds := float64(defaultTimeSyncWarningSeconds), float64(defaultTimeSyncCriticalSeconds)
+	if p.config.WarningSeconds != nil {
+		warningSeconds = *p.config.WarningSeconds
+	}
+	if p.config.CriticalSeconds != nil {
+		criticalSeconds = *p.config.CriticalSeconds
+	}
+	return time.Duration(warningSeconds * float64(time.Second)), time.Duration(criticalSeconds * float64(time.Second))
+}
+
+// Probe implements Prober.
+func (p *timeSyncProber) Probe() *ProbeResult {
+	status := StatusOK
+	var messages []string
+
+	ts, err := p.readTimeSync()
+	switch {
+	case errors.Is(err, util.ErrTimeSyncNotSupported):
+	case err != nil:
+		status = StatusUnknown
+		messages = append(messages, err.Error())
+	case !ts.Synchronized:
+		status = StatusCritical
+		messages = append(messages, "clock is not synchronized")
+	default:
+		messages = append(messages, fmt.Sprintf("clock is synchronized (estimated error %s)", ts.EstimatedError))
+	}
+
+	offset, ok := p.stats.ClockOffset()
+	if ok {
+		warning, critical := p.thresholds()
+		msg := fmt.Sprintf("offset from Mackerel API is %s", offset.Round(100*time.Millisecond))
+		abs := time.Duration(math.Abs(float64(offset)))
+		switch {
+		case abs >= critical:
+			status = worseStatus(status, StatusCritical)
+			msg += fmt.Sprintf(" (>= %s)", critical)
+		case abs >= warning:
+			status = worseStatus(status, StatusWarning)
+			msg += fmt.Sprintf(" (>= %s)", warning)
+		}
+		messages = append(messages, msg)
+	}
+
+	if len(messages) == 0 {
+		return &ProbeResult{Status: StatusUnknown, Message: "neither the state of time synchronization nor the offset from Mackerel API is available"}
+	}
+	return &ProbeResult{Status: status, Message: strings.Join(messages, "; ")}
+}
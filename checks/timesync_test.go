@@ -0,0 +1,97 @@
+package checks
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mackerelio/mackerel-agent/config"
+	"github.com/mackerelio/mackerel-agent/metrics"
+	"github.com/mackerelio/mackerel-agent/util"
+)
+
+func TestTimeSyncProber(t *testing.T) {
+	synced := &util.TimeSync{Synchronized: true, EstimatedError: 2 * time.Millisecond}
+	unsynced := &util.TimeSync{Synchronized: false}
+	warningSeconds := 1.0
+
+	tests := []struct {
+		name     string
+		config   config.TimeSyncCheck
+		timeSync *util.TimeSync
+		err      error
+		offset   *time.Duration
+		status   Status
+		msg      string
+	}{
+		{
+			name:     "synchronized",
+			timeSync: synced,
+			status:   StatusOK,
+			msg:      "clock is synchronized (estimated error 2ms)",
+		},
+		{
+			name:     "unsynchronized",
+			timeSync: unsynced,
+			status:   StatusCritical,
+			msg:      "clock is not synchronized",
+		},
+		{
+			name:     "small offset",
+			timeSync: synced,
+			offset:   durationPtr(-800 * time.Millisecond),
+			status:   StatusOK,
+			msg:      "clock is synchronized (estimated error 2ms); offset from Mackerel API is -800ms",
+		},
+		{
+			name:     "warning offset",
+			config:   config.TimeSyncCheck{WarningSeconds: &warningSeconds},
+			timeSync: synced,
+			offset:   durationPtr(-1500 * time.Millisecond),
+			status:   StatusWarning,
+			msg:      "clock is synchronized (estimated error 2ms); offset from Mackerel API is -1.5s (>= 1s)",
+		},
+		{
+			name:   "critical offset without adjtimex",
+			err:    util.ErrTimeSyncNotSupported,
+			offset: durationPtr(30 * time.Second),
+			status: StatusCritical,
+			msg:    "offset from Mackerel API is 30s (>= 10s)",
+		},
+		{
+			name:   "nothing available",
+			err:    util.ErrTimeSyncNotSupported,
+			status: StatusUnknown,
+			msg:    "neither the state of time synchronization nor the offset from Mackerel API is available",
+		},
+		{
+			name:   "adjtimex failure",
+			err:    errors.New("operation not permitted"),
+			status: StatusUnknown,
+			msg:    "operation not permitted",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats := &metrics.AgentStats{}
+			if tt.offset != nil {
+				stats.RecordClockOffset(*tt.offset)
+			}
+			p := NewProber("clock", &config.CheckPlugin{TimeSync: &tt.config}, stats).(*timeSyncProber)
+			p.readTimeSync = func() (*util.TimeSync, error) {
+				return tt.timeSync, tt.err
+			}
+			result := p.Probe()
+			if result.Status != tt.status {
+				t.Errorf("status should be %s but %s: %s", tt.status, result.Status, result.Message)
+			}
+			if result.Message != tt.msg {
+				t.Errorf("message should be %q but %q", tt.msg, result.Message)
+			}
+		})
+	}
+}
+
+func durationPtr(d time.Duration) *time.Duration {
+	return &d
+}
@@ -57,7 +57,8 @@ func RunCommandContext(ctx context.Context, command string, opt CommandOption) (
 	return RunCommandArgsContext(ctx, cmdArgs, opt)
 }
 
-var errTimedOut = errors.New("command timed out")
+// ErrTimedOut is returned when the command is killed because of timeout.
+var ErrTimedOut = errors.New("command timed out")
 
 // RunCommandArgs run the command
 func RunCommandArgs(cmdArgs []string, opt CommandOption) (stdout, stderr string, exitCode int, err error) {
@@ -93,7 +94,7 @@ func RunCommandArgsContext(ctx context.Context, cmdArgs []string, opt CommandOpt
 	stderr = decodeBytes(errbuf)
 	exitCode = -1
 	if err == nil && exitStatus.IsTimedOut() && (runtime.GOOS == "windows" || exitStatus.Signaled) {
-		err = errTimedOut
+		err = ErrTimedOut
 		exitCode = exitStatus.GetChildExitCode()
 	}
 	if err != nil {
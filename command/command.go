@@ -18,6 +18,7 @@ import (
 	"github.com/mackerelio/mackerel-agent/mackerel"
 	"github.com/mackerelio/mackerel-agent/metrics"
 	"github.com/mackerelio/mackerel-agent/spec"
+	"github.com/mackerelio/mackerel-agent/util"
 	mkr "github.com/mackerelio/mackerel-client-go"
 )
 
@@ -167,6 +168,9 @@ func prepareCustomIdentiferHosts(conf *config.Config, api *mackerel.API) map[str
 // Interval between each updating host specs.
 var specsUpdateInterval = 1 * time.Hour
 
+// Interval between each retrying to create graph definitions.
+var graphDefsRetryInterval = 10 * time.Minute
+
 func delayByHost(host *mkr.Host) int {
 	s := sha1.Sum([]byte(host.ID))
 	return int(s[len(s)-1]) % int(config.PostMetricsInterval.Seconds())
@@ -208,7 +212,12 @@ func loop(app *App, termCh chan struct{}) error {
 	// Periodically update host specs.
 	go updateHostSpecsLoop(ctx, app)
 
+	if app.Config.Uptime.Enabled {
+		go reportReboot(ctx, app)
+	}
+
 	postQueue := make(chan *postValue, postMetricsBufferSize)
+	app.Agent.Stats.SetPostQueueLengthFunc(func() int { return len(postQueue) })
 	go enqueueLoop(ctx, app, postQueue)
 
 	postDelaySeconds := delayByHost(app.Host)
@@ -218,7 +227,9 @@ func loop(app *App, termCh chan struct{}) error {
 	case <-termCh:
 		return nil
 	case <-time.After(time.Duration(initialDelay) * time.Second):
-		app.Agent.InitPluginGenerators(app.API)
+		if err := app.Agent.InitPluginGenerators(app.API); err != nil {
+			go retryInitPluginGenerators(ctx, app)
+		}
 	}
 
 	termMetricsCh := make(chan struct{})
@@ -324,6 +335,7 @@ func loop(app *App, termCh chan struct{}) error {
 				postValues = append(postValues, v.values...)
 			}
 			err := postHostMetricValuesWithRetry(app, postValues)
+			app.Agent.Stats.RecordPost(err)
 			if err != nil {
 				if lState != loopStateTerminating {
 					lState = loopStateHadError
@@ -358,7 +370,7 @@ func loop(app *App, termCh chan struct{}) error {
 func postHostMetricValuesWithRetry(app *App, postValues []*mkr.HostMetricValue) error {
 	deadline := time.Now().Add(25 * time.Second)
 
-	err := app.API.PostHostMetricValues(postValues)
+	err := postHostMetricValues(app, postValues)
 	if err == nil {
 		logger.Debugf("Posting metrics succeeded.")
 		return err
@@ -367,7 +379,7 @@ func postHostMetricValuesWithRetry(app *App, postValues []*mkr.HostMetricValue)
 	// If first request did not take so long and it failed on network error, retry once immedeately
 	if time.Now().Before(deadline) && mackerel.IsNetworkError(err) {
 		logger.Warningf("Failed to post metrics value (will retry immediately): %s", err.Error())
-		err = app.API.PostHostMetricValues(postValues)
+		err = postHostMetricValues(app, postValues)
 		if err == nil {
 			logger.Debugf("Posting metrics recovered.")
 			return nil
@@ -377,7 +389,28 @@ func postHostMetricValuesWithRetry(app *App, postValues []*mkr.HostMetricValue)
 	return err
 }
 
+func postHostMetricValues(app *App, postValues []*mkr.HostMetricValue) error {
+	startedAt := time.Now()
+	err := app.API.PostHostMetricValues(postValues)
+	app.Agent.Stats.RecordPostLatency(time.Since(startedAt))
+	return err
+}
+
+func retryInitPluginGenerators(ctx context.Context, app *App) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(graphDefsRetryInterval):
+			if err := app.Agent.InitPluginGenerators(app.API); err == nil {
+				return
+			}
+		}
+	}
+}
+
 func updateHostSpecsLoop(ctx context.Context, app *App) {
+	changes := watchHostSpecChanges(ctx, app.Config)
 	for {
 		app.UpdateHostSpecs()
 		select {
@@ -385,6 +418,8 @@ func updateHostSpecsLoop(ctx context.Context, app *App) {
 			return
 		case <-time.After(specsUpdateInterval):
 			// nop
+		case <-changes:
+			logger.Infof("Updating host specs since network interfaces or the hostname changed")
 		}
 	}
 }
@@ -499,6 +534,7 @@ func runCheckersLoop(ctx context.Context, app *App, termCheckerCh <-chan struct{
 	// Do not block checking.
 	checkReportCh := make(chan *checks.Report, reportCheckBufferSize*len(app.Agent.Checkers))
 	reportImmediateCh := make(chan struct{}, reportCheckBufferSize*len(app.Agent.Checkers))
+	app.Agent.Stats.SetCheckReportBacklogFunc(func() int { return len(checkReportCh) })
 
 	for _, checker := range app.Agent.Checkers {
 		go runChecker(ctx, checker, checkReportCh, reportImmediateCh)
@@ -617,12 +653,16 @@ func reportCheckMonitorsInternal(app *App, hostID string, reports []*checks.Repo
 
 // collectHostParam collects host specs (correspond to "name", "meta", "interfaces" and "customIdentifier" fields in API v0)
 func collectHostParam(conf *config.Config, ameta *AgentMeta) (*mkr.CreateHostParam, error) {
-	hostname, err := os.Hostname()
-	if err != nil {
-		return nil, fmt.Errorf("failed to obtain hostname: %s", err.Error())
+	hostname := conf.Hostname
+	if hostname == "" {
+		h, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("failed to obtain hostname: %s", err.Error())
+		}
+		hostname = h
 	}
 
-	specGens := specGenerators()
+	specGens := specGenerators(conf)
 	cGen := spec.CloudGeneratorSuggester.Suggest(conf)
 	if cGen != nil {
 		specGens = append(specGens, cGen)
@@ -630,6 +670,7 @@ func collectHostParam(conf *config.Config, ameta *AgentMeta) (*mkr.CreateHostPar
 	meta := spec.Collect(specGens)
 
 	var customIdentifier string
+	var err error
 	if cGen != nil {
 		customIdentifier, err = cGen.SuggestCustomIdentifier()
 		if err != nil {
@@ -646,26 +687,29 @@ func collectHostParam(conf *config.Config, ameta *AgentMeta) (*mkr.CreateHostPar
 	meta.AgentRevision = ameta.Revision
 	meta.AgentName = buildUA(ameta.Version, ameta.Revision)
 
-	checks := make([]mkr.CheckConfig, 0, len(conf.CheckPlugins))
+	checkConfigs := make([]mkr.CheckConfig, 0, len(conf.CheckPlugins))
 	for name, checkPlugin := range conf.CheckPlugins {
 		// Exclude checks with customIdentifiers, which is not for the host itself.
 		if checkPlugin.CustomIdentifier != nil {
 			continue
 		}
-		checks = append(checks,
+		checkConfigs = append(checkConfigs,
 			mkr.CheckConfig{
 				Name: name,
 				Memo: checkPlugin.Memo,
 			})
 	}
+	if conf.SelfCheck.Enabled {
+		checkConfigs = append(checkConfigs, mkr.CheckConfig{Name: checks.SelfCheckName})
+	}
 
 	return &mkr.CreateHostParam{
 		Name:             hostname,
 		Meta:             meta,
 		Interfaces:       interfaces,
 		RoleFullnames:    conf.Roles,
-		Checks:           checks,
-		DisplayName:      conf.DisplayName,
+		Checks:           checkConfigs,
+		DisplayName:      expandDisplayName(conf, hostname, meta.Cloud),
 		CustomIdentifier: customIdentifier,
 	}, nil
 }
@@ -708,6 +752,7 @@ func NewMackerelClient(apibase, apikey, ver, rev string, verbose bool) (*mackere
 // Prepare sets up API and registers the host data to the Mackerel server.
 // Use returned values to call Run().
 func Prepare(conf *config.Config, ameta *AgentMeta) (*App, error) {
+	util.SetIgnoredFSTypes(conf.Filesystems.IgnoreFSTypes)
 	api, err := NewMackerelClient(conf.Apibase, conf.Apikey, ameta.Version, ameta.Revision, conf.Verbose)
 	if err != nil {
 		return nil, fmt.Errorf("failed to prepare an api: %s", err.Error())
@@ -717,9 +762,13 @@ func Prepare(conf *config.Config, ameta *AgentMeta) (*App, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to prepare host: %s", err.Error())
 	}
+	keepRegisteredDisplayName(host)
+
+	ag := NewAgent(conf)
+	api.RecordClockOffset = ag.Stats.RecordClockOffset
 
 	return &App{
-		Agent:                 NewAgent(conf),
+		Agent:                 ag,
 		Config:                conf,
 		Host:                  host,
 		API:                   api,
@@ -748,6 +797,7 @@ func RunOnce(conf *config.Config, ameta *AgentMeta) error {
 }
 
 func runOncePayload(conf *config.Config, ameta *AgentMeta) ([]*mkr.GraphDefsParam, *mkr.CreateHostParam, *agent.MetricsResult, error) {
+	util.SetIgnoredFSTypes(conf.Filesystems.IgnoreFSTypes)
 	hostParam, err := collectHostParam(conf, ameta)
 	if err != nil {
 		logger.Errorf("While collecting host specs: %s", err)
@@ -761,17 +811,42 @@ func runOncePayload(conf *config.Config, ameta *AgentMeta) ([]*mkr.GraphDefsPara
 	}()
 	ag := NewAgent(conf)
 	graphdefs := ag.CollectGraphDefsOfPlugins()
+	// Generators such as cpu, disk and interface calculate values against
+	// the previous collection, so take the first sample in advance.
+	// The commands of metrics plugins are not run twice.
+	for _, g := range ag.MetricsGenerators {
+		g.Generate() // nolint
+	}
+	for _, g := range ag.PluginGenerators {
+		if !metrics.IsCommandPlugin(g) {
+			g.Generate() // nolint
+		}
+	}
+	time.Sleep(metricsInterval)
 	metrics := ag.CollectMetrics(time.Now())
 	return graphdefs, hostParam, metrics, nil
 }
 
 // NewAgent creates a new instance of agent.Agent from its configuration conf.
 func NewAgent(conf *config.Config) *agent.Agent {
+	stats := &metrics.AgentStats{}
+	checkers := createCheckers(conf, stats)
+	if conf.SelfCheck.Enabled {
+		checkers = append(checkers, createSelfChecker(conf, stats, checkers))
+	}
+	generators := pluginGenerators(conf, stats)
+	for _, checker := range checkers {
+		// built-in checks may post metric values observed while checking.
+		if g := checker.MetricsGenerator(); g != nil {
+			generators = append(generators, g)
+		}
+	}
 	return &agent.Agent{
 		MetricsGenerators:  prepareGenerators(conf),
-		PluginGenerators:   pluginGenerators(conf),
-		Checkers:           createCheckers(conf),
+		PluginGenerators:   generators,
+		Checkers:           checkers,
 		MetadataGenerators: metadataGenerators(conf),
+		Stats:              stats,
 	}
 }
 
@@ -790,13 +865,14 @@ func Run(app *App, termCh chan struct{}) error {
 	return err
 }
 
-func createCheckers(conf *config.Config) []*checks.Checker {
+func createCheckers(conf *config.Config, stats *metrics.AgentStats) []*checks.Checker {
 	checkers := []*checks.Checker{}
 
 	for name, pluginConfig := range conf.CheckPlugins {
 		checker := &checks.Checker{
 			Name:   name,
 			Config: pluginConfig,
+			Prober: checks.NewProber(name, pluginConfig, stats),
 		}
 		logger.Debugf("Checker created: %v", checker)
 		checkers = append(checkers, checker)
@@ -805,18 +881,59 @@ func createCheckers(conf *config.Config) []*checks.Checker {
 	return checkers
 }
 
+// createSelfChecker creates the checker which monitors the agent itself
+// including the other checkers.
+func createSelfChecker(conf *config.Config, stats *metrics.AgentStats, checkers []*checks.Checker) *checks.Checker {
+	checker := &checks.Checker{
+		Name:   checks.SelfCheckName,
+		Config: &config.CheckPlugin{},
+		Prober: checks.NewSelfProber(&conf.SelfCheck, stats, checkers),
+	}
+	logger.Debugf("Checker created: %v", checker)
+	return checker
+}
+
 func prepareGenerators(conf *config.Config) []metrics.Generator {
 	return metricsGenerators(conf)
 }
 
-func pluginGenerators(conf *config.Config) []metrics.PluginGenerator {
+// sampled wraps g, which generates the family of metrics, to sample it more
+// often than posting if the family is configured so.
+func sampled(conf *config.Config, family string, g metrics.Generator) metrics.Generator {
+	s, ok := conf.Sampling[family]
+	if !ok || s.IntervalSeconds <= 0 {
+		return g
+	}
+	return &metrics.SamplingGenerator{
+		Family:       family,
+		Generator:    g,
+		Interval:     time.Duration(s.IntervalSeconds) * time.Second,
+		Aggregations: s.Aggregations,
+	}
+}
+
+func pluginGenerators(conf *config.Config, stats *metrics.AgentStats) []metrics.PluginGenerator {
 	generators := []metrics.PluginGenerator{}
-	for _, pluginConfig := range conf.MetricPlugins {
-		generators = append(generators, metrics.NewPluginGenerator(pluginConfig))
+	for name, pluginConfig := range conf.MetricPlugins {
+		generators = append(generators, metrics.NewPluginGenerator(name, pluginConfig, stats))
+	}
+
+	generators = append(generators, customMetricsGenerators(conf)...)
+
+	if conf.Docker.Enabled {
+		generators = append(generators, &metrics.DockerGenerator{Socket: conf.Docker.Socket})
+	}
+
+	if conf.Uptime.Enabled {
+		generators = append(generators, &metrics.UptimeGenerator{})
+	}
+
+	if conf.TimeSync.Enabled {
+		generators = append(generators, &metrics.TimeSyncGenerator{Stats: stats, APIOffset: conf.TimeSync.APIOffset})
 	}
 
 	if conf.Diagnostic {
-		generators = append(generators, &metrics.AgentGenerator{})
+		generators = append(generators, &metrics.AgentGenerator{Stats: stats})
 	}
 	return generators
 }
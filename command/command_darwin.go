@@ -8,7 +8,7 @@ import (
 	specDarwin "github.com/mackerelio/mackerel-agent/spec/darwin"
 )
 
-func specGenerators() []spec.Generator {
+func specGenerators(conf *config.Config) []spec.Generator {
 	return []spec.Generator{
 		&specDarwin.KernelGenerator{},
 		&specDarwin.MemoryGenerator{},
@@ -23,12 +23,16 @@ func interfaceGenerator() spec.InterfaceGenerator {
 
 func metricsGenerators(conf *config.Config) []metrics.Generator {
 	generators := []metrics.Generator{
-		&metrics.LoadavgGenerator{},
-		&metricsDarwin.CPUUsageGenerator{},
-		&metricsDarwin.MemoryGenerator{},
-		&metrics.FilesystemGenerator{IgnoreRegexp: conf.Filesystems.Ignore.Regexp, UseMountpoint: conf.Filesystems.UseMountpoint},
-		&metrics.InterfaceGenerator{IgnoreRegexp: conf.Interfaces.Ignore.Regexp, Interval: metricsInterval},
+		sampled(conf, "loadavg", &metrics.LoadavgGenerator{}),
+		sampled(conf, "cpu", &metricsDarwin.CPUUsageGenerator{}),
+		sampled(conf, "memory", &metricsDarwin.MemoryGenerator{}),
+		sampled(conf, "filesystem", &metrics.FilesystemGenerator{IgnoreRegexp: conf.Filesystems.Ignore.Regexp, UseMountpoint: conf.Filesystems.UseMountpoint}),
+		sampled(conf, "interface", &metrics.InterfaceGenerator{IgnoreRegexp: conf.Interfaces.Ignore.Regexp}),
 	}
 
 	return generators
 }
+
+func customMetricsGenerators(conf *config.Config) []metrics.PluginGenerator {
+	return nil
+}
@@ -8,7 +8,7 @@ import (
 	specFreebsd "github.com/mackerelio/mackerel-agent/spec/freebsd"
 )
 
-func specGenerators() []spec.Generator {
+func specGenerators(conf *config.Config) []spec.Generator {
 	return []spec.Generator{
 		&specFreebsd.KernelGenerator{},
 		&specFreebsd.MemoryGenerator{},
@@ -23,12 +23,16 @@ func interfaceGenerator() spec.InterfaceGenerator {
 
 func metricsGenerators(conf *config.Config) []metrics.Generator {
 	generators := []metrics.Generator{
-		&metrics.LoadavgGenerator{},
-		&metricsFreebsd.CPUUsageGenerator{},
-		&metrics.FilesystemGenerator{IgnoreRegexp: conf.Filesystems.Ignore.Regexp, UseMountpoint: conf.Filesystems.UseMountpoint},
-		&metricsFreebsd.MemoryGenerator{},
-		&metrics.InterfaceGenerator{IgnoreRegexp: conf.Interfaces.Ignore.Regexp, Interval: metricsInterval},
+		sampled(conf, "loadavg", &metrics.LoadavgGenerator{}),
+		sampled(conf, "cpu", &metricsFreebsd.CPUUsageGenerator{}),
+		sampled(conf, "filesystem", &metrics.FilesystemGenerator{IgnoreRegexp: conf.Filesystems.Ignore.Regexp, UseMountpoint: conf.Filesystems.UseMountpoint}),
+		sampled(conf, "memory", &metricsFreebsd.MemoryGenerator{}),
+		sampled(conf, "interface", &metrics.InterfaceGenerator{IgnoreRegexp: conf.Interfaces.Ignore.Regexp}),
 	}
 
 	return generators
 }
+
+func customMetricsGenerators(conf *config.Config) []metrics.PluginGenerator {
+	return nil
+}
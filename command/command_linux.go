@@ -6,13 +6,19 @@ import (
 	metricsLinux "github.com/mackerelio/mackerel-agent/metrics/linux"
 	"github.com/mackerelio/mackerel-agent/spec"
 	specLinux "github.com/mackerelio/mackerel-agent/spec/linux"
+	"github.com/mackerelio/mackerel-agent/util"
 )
 
-func specGenerators() []spec.Generator {
+func specGenerators(conf *config.Config) []spec.Generator {
+	var cpuGenerator, memoryGenerator spec.Generator = &specLinux.CPUGenerator{}, &specLinux.MemoryGenerator{}
+	// The failure is logged by metricsGenerators.
+	if cgroup, err := cgroupView(conf); err == nil && cgroup != nil {
+		cpuGenerator, memoryGenerator = &specLinux.CgroupCPUGenerator{Cgroup: cgroup}, &specLinux.CgroupMemoryGenerator{Cgroup: cgroup}
+	}
 	return []spec.Generator{
 		&specLinux.KernelGenerator{},
-		&specLinux.CPUGenerator{},
-		&specLinux.MemoryGenerator{},
+		cpuGenerator,
+		memoryGenerator,
 		&specLinux.BlockDeviceGenerator{},
 		&spec.FilesystemGenerator{},
 	}
@@ -22,15 +28,74 @@ func interfaceGenerator() spec.InterfaceGenerator {
 	return &specLinux.InterfaceGenerator{}
 }
 
+// cgroupView returns the cgroup v2 of the agent if the CPU and memory
+// metrics should describe it instead of the host.
+func cgroupView(conf *config.Config) (*util.Cgroup, error) {
+	if conf.Cgroup.View != config.CgroupViewCgroup {
+		return nil, nil
+	}
+	return util.DetectCgroupV2()
+}
+
 func metricsGenerators(conf *config.Config) []metrics.Generator {
+	var cpuGenerator, memoryGenerator metrics.Generator = &metricsLinux.CPUUsageGenerator{}, &metricsLinux.MemoryGenerator{}
+	cgroup, err := cgroupView(conf)
+	if err != nil {
+		logger.Warningf("Failed to detect cgroup, post CPU and memory metrics of the host instead: %s", err)
+	} else if cgroup != nil {
+		cpuGenerator, memoryGenerator = &metricsLinux.CgroupCPUGenerator{Cgroup: cgroup}, &metricsLinux.CgroupMemoryGenerator{Cgroup: cgroup}
+	}
+
 	generators := []metrics.Generator{
-		&metrics.LoadavgGenerator{},
-		&metricsLinux.CPUUsageGenerator{Interval: metricsInterval},
-		&metricsLinux.MemoryGenerator{},
-		&metrics.InterfaceGenerator{IgnoreRegexp: conf.Interfaces.Ignore.Regexp, Interval: metricsInterval},
-		&metricsLinux.DiskGenerator{IgnoreRegexp: conf.Disks.Ignore.Regexp, Interval: metricsInterval, UseMountpoint: conf.Filesystems.UseMountpoint},
-		&metrics.FilesystemGenerator{IgnoreRegexp: conf.Filesystems.Ignore.Regexp, UseMountpoint: conf.Filesystems.UseMountpoint},
+		sampled(conf, "loadavg", &metrics.LoadavgGenerator{}),
+		sampled(conf, "cpu", cpuGenerator),
+		sampled(conf, "memory", memoryGenerator),
+		sampled(conf, "interface", &metrics.InterfaceGenerator{IgnoreRegexp: conf.Interfaces.Ignore.Regexp}),
+		sampled(conf, "disk", &metricsLinux.DiskGenerator{IgnoreRegexp: conf.Disks.Ignore.Regexp, UseMountpoint: conf.Filesystems.UseMountpoint}),
+		sampled(conf, "filesystem", &metrics.FilesystemGenerator{IgnoreRegexp: conf.Filesystems.Ignore.Regexp, UseMountpoint: conf.Filesystems.UseMountpoint}),
 	}
 
 	return generators
 }
+
+func customMetricsGenerators(conf *config.Config) []metrics.PluginGenerator {
+	var generators []metrics.PluginGenerator
+	// The failure has been logged by metricsGenerators.
+	if cgroup, err := cgroupView(conf); err == nil && cgroup != nil {
+		generators = append(generators, &metricsLinux.CgroupThrottlingGenerator{Cgroup: cgroup})
+	}
+	if conf.CPU.PerCore {
+		generators = append(generators, &metricsLinux.CPUCoreGenerator{})
+	}
+	if conf.Pressure.Enabled {
+		generators = append(generators, &metricsLinux.PressureGenerator{})
+	}
+	if conf.Memory.ExtendedMetrics {
+		generators = append(generators, &metricsLinux.VmstatGenerator{})
+	}
+	if conf.Kernel.Enabled {
+		generators = append(generators, &metricsLinux.KernelGenerator{})
+	}
+	if conf.TCP.Enabled {
+		generators = append(generators, &metricsLinux.TCPGenerator{})
+	}
+	if conf.Systemd.Enabled {
+		generators = append(generators, &metricsLinux.SystemdGenerator{IncludeRegexp: conf.Systemd.Include.Regexp, ExcludeRegexp: conf.Systemd.Exclude.Regexp})
+	}
+	if conf.Hwmon.Enabled {
+		generators = append(generators, &metricsLinux.HwmonGenerator{})
+	}
+	if conf.RAID.Enabled {
+		generators = append(generators, &metricsLinux.RAIDGenerator{})
+	}
+	if conf.Disks.ExtendedMetrics {
+		generators = append(generators, &metricsLinux.DiskIOGenerator{IgnoreRegexp: conf.Disks.Ignore.Regexp, UseMountpoint: conf.Filesystems.UseMountpoint})
+	}
+	if conf.Interfaces.ExtendedMetrics {
+		generators = append(generators, &metricsLinux.InterfaceStatsGenerator{IgnoreRegexp: conf.Interfaces.Ignore.Regexp})
+	}
+	if conf.Filesystems.ExtendedMetrics {
+		generators = append(generators, &metricsLinux.FilesystemStatsGenerator{IgnoreRegexp: conf.Filesystems.Ignore.Regexp, UseMountpoint: conf.Filesystems.UseMountpoint})
+	}
+	return generators
+}
@@ -8,7 +8,7 @@ import (
 	specNetbsd "github.com/mackerelio/mackerel-agent/spec/netbsd"
 )
 
-func specGenerators() []spec.Generator {
+func specGenerators(conf *config.Config) []spec.Generator {
 	return []spec.Generator{
 		&specNetbsd.KernelGenerator{},
 		&specNetbsd.MemoryGenerator{},
@@ -23,12 +23,16 @@ func interfaceGenerator() spec.InterfaceGenerator {
 
 func metricsGenerators(conf *config.Config) []metrics.Generator {
 	generators := []metrics.Generator{
-		&metrics.LoadavgGenerator{},
-		&metricsNetbsd.CPUUsageGenerator{},
-		&metrics.FilesystemGenerator{IgnoreRegexp: conf.Filesystems.Ignore.Regexp, UseMountpoint: conf.Filesystems.UseMountpoint},
-		&metricsNetbsd.MemoryGenerator{},
-		&metrics.InterfaceGenerator{IgnoreRegexp: conf.Interfaces.Ignore.Regexp, Interval: metricsInterval},
+		sampled(conf, "loadavg", &metrics.LoadavgGenerator{}),
+		sampled(conf, "cpu", &metricsNetbsd.CPUUsageGenerator{}),
+		sampled(conf, "filesystem", &metrics.FilesystemGenerator{IgnoreRegexp: conf.Filesystems.Ignore.Regexp, UseMountpoint: conf.Filesystems.UseMountpoint}),
+		sampled(conf, "memory", &metricsNetbsd.MemoryGenerator{}),
+		sampled(conf, "interface", &metrics.InterfaceGenerator{IgnoreRegexp: conf.Interfaces.Ignore.Regexp}),
 	}
 
 	return generators
 }
+
+func customMetricsGenerators(conf *config.Config) []metrics.PluginGenerator {
+	return nil
+}
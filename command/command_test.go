@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"runtime"
 	"sort"
 	"sync"
 	"testing"
@@ -262,6 +263,24 @@ func TestCollectHostParamWithChecks(t *testing.T) {
 	}
 }
 
+func TestCollectHostParamWithSelfCheck(t *testing.T) {
+	conf := config.Config{SelfCheck: config.SelfCheck{Enabled: true}}
+	hostParam, err := collectHostParam(&conf, &AgentMeta{})
+
+	if err != nil {
+		t.Errorf("collectHostParam should not fail: %s", err)
+	}
+
+	if len(hostParam.Checks) != 1 || hostParam.Checks[0].Name != checks.SelfCheckName {
+		t.Errorf("self check should be included in param: %v", hostParam.Checks)
+	}
+
+	ag := NewAgent(&conf)
+	if len(ag.Checkers) != 1 || ag.Checkers[0].Name != checks.SelfCheckName {
+		t.Errorf("self checker should be created: %v", ag.Checkers)
+	}
+}
+
 type counterGenerator struct {
 	counter int
 	sync.Mutex
@@ -733,3 +752,41 @@ func TestReportCheckMonitors_NetworkErrorWithRecovery(t *testing.T) {
 	}
 
 }
+
+func TestSampled(t *testing.T) {
+	conf := &config.Config{
+		Sampling: map[string]*config.Sampling{
+			"cpu": {IntervalSeconds: 10, Aggregations: []string{"max"}},
+		},
+	}
+	g := &counterGenerator{}
+	if got := sampled(conf, "memory", g); got != g {
+		t.Errorf("generator should not be wrapped: %#v", got)
+	}
+	s, ok := sampled(conf, "cpu", g).(*metrics.SamplingGenerator)
+	if !ok {
+		t.Fatal("generator should be wrapped by SamplingGenerator")
+	}
+	if s.Family != "cpu" || s.Interval != 10*time.Second || s.Generator != g {
+		t.Errorf("unexpected SamplingGenerator: %#v", s)
+	}
+}
+
+func TestRunOncePayload_rates(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("kernel activity metrics are collected only on Linux")
+	}
+	conf := &config.Config{
+		Kernel: config.Kernel{Enabled: true},
+	}
+	_, _, result, err := runOncePayload(conf, &AgentMeta{})
+	if err != nil {
+		t.Fatalf("should not raise error: %s", err)
+	}
+	if len(result.Values) != 1 {
+		t.Fatalf("there must be some metric values")
+	}
+	if _, ok := result.Values[0].Values["custom.kernel.activity.context_switches"]; !ok {
+		t.Errorf("the rate of custom generators should be collected: %v", result.Values[0].Values)
+	}
+}
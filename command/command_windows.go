@@ -8,7 +8,7 @@ import (
 	specWindows "github.com/mackerelio/mackerel-agent/spec/windows"
 )
 
-func specGenerators() []spec.Generator {
+func specGenerators(conf *config.Config) []spec.Generator {
 	return []spec.Generator{
 		&specWindows.KernelGenerator{},
 		&specWindows.CPUGenerator{},
@@ -31,20 +31,24 @@ func metricsGenerators(conf *config.Config) []metrics.Generator {
 		generators = append(generators, g)
 	}
 	if g, err = metricsWindows.NewCPUUsageGenerator(); err == nil {
-		generators = append(generators, g)
+		generators = append(generators, sampled(conf, "cpu", g))
 	}
 	if g, err = metricsWindows.NewMemoryGenerator(); err == nil {
-		generators = append(generators, g)
+		generators = append(generators, sampled(conf, "memory", g))
 	}
 	if g, err = metricsWindows.NewFilesystemGenerator(conf.Filesystems.Ignore.Regexp); err == nil {
-		generators = append(generators, g)
+		generators = append(generators, sampled(conf, "filesystem", g))
 	}
-	if g, err = metricsWindows.NewInterfaceGenerator(conf.Interfaces.Ignore.Regexp, metricsInterval); err == nil {
-		generators = append(generators, g)
+	if g, err = metricsWindows.NewInterfaceGenerator(conf.Interfaces.Ignore.Regexp); err == nil {
+		generators = append(generators, sampled(conf, "interface", g))
 	}
-	if g, err = metricsWindows.NewDiskGenerator(conf.Disks.Ignore.Regexp, metricsInterval); err == nil {
-		generators = append(generators, g)
+	if g, err = metricsWindows.NewDiskGenerator(conf.Disks.Ignore.Regexp); err == nil {
+		generators = append(generators, sampled(conf, "disk", g))
 	}
 
 	return generators
 }
+
+func customMetricsGenerators(conf *config.Config) []metrics.PluginGenerator {
+	return nil
+}
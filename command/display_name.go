@@ -0,0 +1,114 @@
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/mackerelio/mackerel-agent/config"
+	mkr "github.com/mackerelio/mackerel-client-go"
+)
+
+// displayNameData is the data which display_name templates refer to, e.g.
+//
+//	display_name = "{{.Env.SERVICE}}-{{index .Cloud.Metadata \"instance-id\"}}"
+type displayNameData struct {
+	Hostname string
+	Cloud    *displayNameCloud // nil if the cloud metadata is unavailable
+	Env      map[string]string
+	Roles    []string // e.g. "service:role"
+}
+
+type displayNameCloud struct {
+	Provider string
+	Metadata map[string]string // the scalar values of the cloud metadata
+}
+
+// lastDisplayName is the display name rendered last, which is kept when
+// rendering the template fails, e.g. the cloud metadata it refers to is
+// temporarily unavailable, since referring to the fields of nil .Cloud fails.
+var lastDisplayName struct {
+	sync.Mutex
+	value string
+}
+
+// expandDisplayName renders the display name of the host, or returns the one
+// rendered last if the template fails.
+func expandDisplayName(conf *config.Config, hostname string, cloud *mkr.Cloud) string {
+	displayName, err := renderDisplayName(conf.DisplayName, hostname, cloud, conf.Roles)
+	lastDisplayName.Lock()
+	defer lastDisplayName.Unlock()
+	if err != nil {
+		logger.Warningf("Failed to expand display_name, keep %q: %s", lastDisplayName.value, err)
+		return lastDisplayName.value
+	}
+	lastDisplayName.value = displayName
+	return displayName
+}
+
+// keepRegisteredDisplayName makes expandDisplayName keep the display name of
+// the registered host if the template has never been rendered.
+func keepRegisteredDisplayName(host *mkr.Host) {
+	lastDisplayName.Lock()
+	defer lastDisplayName.Unlock()
+	if lastDisplayName.value == "" {
+		lastDisplayName.value = host.DisplayName
+	}
+}
+
+// renderDisplayName expands the template of display_name.
+func renderDisplayName(displayName, hostname string, cloud *mkr.Cloud, roles []string) (string, error) {
+	if !strings.Contains(displayName, "{{") {
+		return displayName, nil
+	}
+	tmpl, err := config.ParseDisplayName(displayName)
+	if err != nil {
+		return "", err
+	}
+	data := displayNameData{
+		Hostname: hostname,
+		Env:      make(map[string]string),
+		Roles:    roles,
+	}
+	for _, e := range os.Environ() {
+		if k, v, ok := strings.Cut(e, "="); ok {
+			data.Env[k] = v
+		}
+	}
+	if cloud != nil {
+		if metadata := cloudMetadataStrings(cloud.MetaData); len(metadata) > 0 {
+			data.Cloud = &displayNameCloud{Provider: cloud.Provider, Metadata: metadata}
+		}
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// cloudMetadataStrings converts the metadata, whose type depends on the
+// cloud platform, to the map of the scalar values.
+func cloudMetadataStrings(metadata any) map[string]string {
+	b, err := json.Marshal(metadata)
+	if err != nil {
+		return nil
+	}
+	var m map[string]any
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil
+	}
+	ret := make(map[string]string)
+	for k, v := range m {
+		switch v := v.(type) {
+		case string:
+			ret[k] = v
+		case float64, bool:
+			ret[k] = fmt.Sprint(v)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,116 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/mackerelio/mackerel-agent/config"
+	mkr "github.com/mackerelio/mackerel-client-go"
+)
+
+func TestRenderDisplayName(t *testing.T) {
+	t.Setenv("MACKEREL_TEST_ENV", "production")
+	cloud := &mkr.Cloud{
+		Provider: "ec2",
+		MetaData: map[string]string{
+			"instance-id":                 "i-0123456789abcdef0",
+			"placement/availability-zone": "ap-northeast-1a",
+		},
+	}
+	roles := []string{"web:app", "web:api"}
+
+	tests := []struct {
+		name        string
+		displayName string
+		cloud       *mkr.Cloud
+		expect      string
+	}{
+		{
+			name:        "fixed",
+			displayName: "app01",
+			cloud:       cloud,
+			expect:      "app01",
+		},
+		{
+			name:        "cloud",
+			displayName: `{{.Cloud.Provider}}-{{index .Cloud.Metadata "instance-id"}} ({{index .Cloud.Metadata "placement/availability-zone"}})`,
+			cloud:       cloud,
+			expect:      "ec2-i-0123456789abcdef0 (ap-northeast-1a)",
+		},
+		{
+			name:        "env, roles and hostname",
+			displayName: `{{.Env.MACKEREL_TEST_ENV}} {{join .Roles ","}} {{.Hostname}}`,
+			cloud:       cloud,
+			expect:      "production web:app,web:api ip-10-0-0-1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := renderDisplayName(tt.displayName, "ip-10-0-0-1", tt.cloud, roles)
+			if err != nil {
+				t.Fatalf("should not raise error: %v", err)
+			}
+			if got != tt.expect {
+				t.Errorf("display name should be %q but %q", tt.expect, got)
+			}
+		})
+	}
+
+	for _, displayName := range []string{
+		"{{.Undefined}}",
+		`{{.Hostname}}-{{index .Cloud.Metadata "instance-id"}}`,
+		"{{.Cloud.Provider}}",
+		"{{.Env.MACKEREL_TEST_UNDEFINED}}",
+	} {
+		if _, err := renderDisplayName(displayName, "ip-10-0-0-1", nil, roles); err == nil {
+			t.Errorf("should raise error for %s without cloud metadata", displayName)
+		}
+	}
+	if _, err := renderDisplayName("{{.Cloud.Provider}}", "ip-10-0-0-1", &mkr.Cloud{Provider: "ec2"}, roles); err == nil {
+		t.Error("should raise error for empty cloud metadata")
+	}
+}
+
+func TestExpandDisplayName(t *testing.T) {
+	defer func() { lastDisplayName.value = "" }()
+	lastDisplayName.value = ""
+	ok := &config.Config{DisplayName: "{{.Hostname}}-app"}
+	broken := &config.Config{DisplayName: "{{.Undefined}}"}
+
+	keepRegisteredDisplayName(&mkr.Host{DisplayName: "registered"})
+	if got := expandDisplayName(broken, "ip-10-0-0-1", nil); got != "registered" {
+		t.Errorf("the display name of the registered host should be kept but %q", got)
+	}
+	if got := expandDisplayName(ok, "ip-10-0-0-1", nil); got != "ip-10-0-0-1-app" {
+		t.Errorf("display name should be rendered but %q", got)
+	}
+	keepRegisteredDisplayName(&mkr.Host{DisplayName: "registered"})
+	if got := expandDisplayName(broken, "ip-10-0-0-2", nil); got != "ip-10-0-0-1-app" {
+		t.Errorf("the display name rendered last should be kept but %q", got)
+	}
+
+	cloud := &config.Config{DisplayName: `{{index .Cloud.Metadata "instance-id"}}`}
+	if got := expandDisplayName(cloud, "ip-10-0-0-1", &mkr.Cloud{Provider: "ec2", MetaData: map[string]string{"instance-id": "i-0123"}}); got != "i-0123" {
+		t.Errorf("display name should be rendered but %q", got)
+	}
+	if got := expandDisplayName(cloud, "ip-10-0-0-1", nil); got != "i-0123" {
+		t.Errorf("the display name rendered last should be kept without cloud metadata but %q", got)
+	}
+}
+
+func TestCloudMetadataStrings(t *testing.T) {
+	m := cloudMetadataStrings(map[string]any{
+		"instanceId": "1234",
+		"vmSize":     2,
+		"spot":       false,
+		"tags":       map[string]string{"env": "prod"},
+	})
+	expect := map[string]string{"instanceId": "1234", "vmSize": "2", "spot": "false"}
+	if len(m) != len(expect) {
+		t.Errorf("metadata should be %v but %v", expect, m)
+	}
+	for k, v := range expect {
+		if m[k] != v {
+			t.Errorf("metadata %s should be %q but %q", k, v, m[k])
+		}
+	}
+}
@@ -10,6 +10,8 @@ import (
 	"github.com/mackerelio/mackerel-agent/config"
 	"github.com/mackerelio/mackerel-agent/mackerel"
 	"github.com/mackerelio/mackerel-agent/metadata"
+	"github.com/mackerelio/mackerel-agent/metrics"
+	"github.com/mackerelio/mackerel-agent/spec"
 	mkr "github.com/mackerelio/mackerel-client-go"
 )
 
@@ -27,9 +29,43 @@ func metadataGenerators(conf *config.Config) []*metadata.Generator {
 		generators = append(generators, generator)
 	}
 
+	if conf.Docker.Enabled {
+		if _, ok := conf.MetadataPlugins[dockerMetadataNamespace]; ok {
+			logger.Warningf("Docker container inventory is not posted since metadata plugin %q is configured", dockerMetadataNamespace)
+		} else {
+			generators = append(generators, &metadata.Generator{
+				Name:      dockerMetadataNamespace,
+				Config:    &config.MetadataPlugin{},
+				Cachefile: filepath.Join(workdir, dockerMetadataNamespace),
+				Fetcher:   (&metrics.DockerGenerator{Socket: conf.Docker.Socket}).Inventory,
+			})
+		}
+	}
+
+	if g, ok := interfaceGenerator().(spec.LinkGenerator); ok && conf.Interfaces.LinkMetadata {
+		if _, ok := conf.MetadataPlugins[interfacesMetadataNamespace]; ok {
+			logger.Warningf("Link states of interfaces are not posted since metadata plugin %q is configured", interfacesMetadataNamespace)
+		} else {
+			generators = append(generators, &metadata.Generator{
+				Name:      interfacesMetadataNamespace,
+				Config:    &config.MetadataPlugin{},
+				Cachefile: filepath.Join(workdir, interfacesMetadataNamespace),
+				Fetcher: func() (any, error) {
+					return g.Links()
+				},
+			})
+		}
+	}
+
 	return generators
 }
 
+// the namespace of the host metadata of Docker container inventory
+const dockerMetadataNamespace = "docker"
+
+// the namespace of the host metadata of link states of network interfaces
+const interfacesMetadataNamespace = "interfaces"
+
 // The directory configuration in the env config of metadata should work as
 // same as metric plugins. Since the working directory of metadata plugin is
 // handled by mackerel-agent (not the plugin process), we have to lookup here.
@@ -0,0 +1,131 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Songmu/retry"
+	"github.com/mackerelio/mackerel-agent/util"
+	mkr "github.com/mackerelio/mackerel-client-go"
+)
+
+// the file in conf.Root which saves the boot time observed last
+const bootTimeFileName = "btime"
+
+// btime in /proc/stat shifts slightly as the clock is adjusted,
+// so boot times closer than this are regarded as the same boot.
+const bootTimeTolerance = time.Minute
+
+// detectReboot compares bt with the boot time saved in root. It does not
+// regard as a reboot if nothing is saved. The caller should save bt by
+// saveBootTime after the reboot is reported.
+func detectReboot(root string, bt time.Time) (prev time.Time, rebooted bool, err error) {
+	file := filepath.Join(root, bootTimeFileName)
+	content, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	sec, err := strconv.ParseInt(strings.TrimSpace(string(content)), 10, 64)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to parse %s: %w", file, err)
+	}
+	prev = time.Unix(sec, 0)
+	if d := bt.Sub(prev); -bootTimeTolerance < d && d < bootTimeTolerance {
+		return prev, false, nil
+	}
+	return prev, true, nil
+}
+
+// saveBootTime saves bt in root as the boot time observed last.
+func saveBootTime(root string, bt time.Time) error {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(root, bootTimeFileName), []byte(strconv.FormatInt(bt.Unix(), 10)), 0644)
+}
+
+// rebootAnnotations returns the graph annotations of the reboot of host
+// for each service which the host belongs to.
+func rebootAnnotations(host *mkr.Host, prev, bt time.Time) []*mkr.GraphAnnotation {
+	services := make([]string, 0, len(host.Roles))
+	for service := range host.Roles {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+
+	annotations := make([]*mkr.GraphAnnotation, 0, len(services))
+	for _, service := range services {
+		annotations = append(annotations, &mkr.GraphAnnotation{
+			Title:       fmt.Sprintf("Reboot: %s", host.Name),
+			Description: fmt.Sprintf("mackerel-agent detected that host %s (%s) booted at %s, previously booted at %s.", host.Name, host.ID, bt.Format(time.RFC3339), prev.Format(time.RFC3339)),
+			From:        bt.Unix(),
+			To:          bt.Unix(),
+			Service:     service,
+			Roles:       host.Roles[service],
+		})
+	}
+	return annotations
+}
+
+// the number and interval of attempts to post the annotations of a reboot
+var (
+	rebootAnnotationAttempts      uint = 3
+	rebootAnnotationRetryInterval      = 10 * time.Second
+)
+
+// reportReboot logs and posts graph annotations if the host has booted
+// since the agent ran last. The boot time is saved only after all the
+// annotations are posted, so that the reboot is reported again on the next
+// start if posting fails.
+func reportReboot(ctx context.Context, app *App) {
+	bt, err := util.ReadBootTime()
+	if err != nil {
+		logger.Warningf("Failed to read the boot time: %s", err)
+		return
+	}
+	if err := reportRebootAt(ctx, app, bt); err != nil {
+		logger.Warningf("Failed to report the reboot: %s", err)
+	}
+}
+
+func reportRebootAt(ctx context.Context, app *App, bt time.Time) error {
+	prev, rebooted, err := detectReboot(app.Config.Root, bt)
+	if err != nil {
+		return err
+	}
+	if rebooted {
+		logger.Warningf("Detected that the host booted at %s (previously booted at %s)", bt.Format(time.RFC3339), prev.Format(time.RFC3339))
+		annotations := rebootAnnotations(app.Host, prev, bt)
+		if len(annotations) == 0 {
+			logger.Infof("The annotation of the reboot is not posted since the host belongs to no services")
+		}
+		err := retry.WithContext(ctx, rebootAnnotationAttempts, rebootAnnotationRetryInterval, func() error {
+			var failed []*mkr.GraphAnnotation
+			for _, annotation := range annotations {
+				if _, err := app.API.CreateGraphAnnotation(annotation); err != nil {
+					logger.Warningf("Failed to post the annotation of the reboot to service %s: %s", annotation.Service, err)
+					failed = append(failed, annotation)
+				}
+			}
+			annotations = failed
+			if len(failed) > 0 {
+				return fmt.Errorf("failed to post the annotations of the reboot to %d services", len(failed))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return saveBootTime(app.Config.Root, bt)
+}
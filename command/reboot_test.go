@@ -0,0 +1,138 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mackerelio/mackerel-agent/mackerel"
+	mkr "github.com/mackerelio/mackerel-client-go"
+)
+
+func TestDetectReboot(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "state")
+	bt := time.Unix(1690000000, 0)
+
+	if _, rebooted, err := detectReboot(root, bt); err != nil || rebooted {
+		t.Errorf("should not detect reboot on the first run: %v, %v", rebooted, err)
+	}
+	if err := saveBootTime(root, bt); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(filepath.Join(root, bootTimeFileName))
+	if err != nil || string(content) != "1690000000" {
+		t.Errorf("boot time should be saved: %q, %v", content, err)
+	}
+
+	// btime shifts slightly as the clock is adjusted.
+	if _, rebooted, err := detectReboot(root, bt.Add(time.Second)); err != nil || rebooted {
+		t.Errorf("should not detect reboot for the same boot: %v, %v", rebooted, err)
+	}
+
+	newBt := bt.Add(24 * time.Hour)
+	prev, rebooted, err := detectReboot(root, newBt)
+	if err != nil || !rebooted {
+		t.Errorf("should detect reboot: %v, %v", rebooted, err)
+	}
+	if !prev.Equal(bt) {
+		t.Errorf("previous boot time should be %s but %s", bt, prev)
+	}
+	if _, rebooted, _ := detectReboot(root, newBt); !rebooted {
+		t.Error("should detect the reboot until the boot time is saved")
+	}
+}
+
+func TestReportRebootAt(t *testing.T) {
+	defer func(attempts uint, interval time.Duration) {
+		rebootAnnotationAttempts, rebootAnnotationRetryInterval = attempts, interval
+	}(rebootAnnotationAttempts, rebootAnnotationRetryInterval)
+	rebootAnnotationAttempts, rebootAnnotationRetryInterval = 2, time.Millisecond
+
+	conf, mockHandlers, _, deferFunc := newMockAPIServer(t)
+	defer deferFunc()
+	api, err := mackerel.NewAPI(conf.Apibase, conf.Apikey, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := &App{
+		Config: &conf,
+		API:    api,
+		Host:   &mkr.Host{ID: "xyzabc12345", Name: "app01", Roles: mkr.Roles{"web": {"app"}, "batch": {"worker"}}},
+	}
+	bt := time.Unix(1690000000, 0)
+	if err := saveBootTime(conf.Root, bt); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	posted := make(map[string]int)
+	fail := true
+	mockHandlers["POST /api/v0/graph-annotations"] = func(w http.ResponseWriter, req *http.Request) (int, jsonObject) {
+		mu.Lock()
+		defer mu.Unlock()
+		var annotation mkr.GraphAnnotation
+		json.NewDecoder(req.Body).Decode(&annotation)
+		if fail && annotation.Service == "web" {
+			return http.StatusInternalServerError, jsonObject{}
+		}
+		posted[annotation.Service]++
+		return http.StatusOK, jsonObject{"id": "abcde"}
+	}
+
+	newBt := bt.Add(24 * time.Hour)
+	if err := reportRebootAt(context.Background(), app, newBt); err == nil {
+		t.Error("should raise error when posting the annotation fails")
+	}
+	if _, rebooted, _ := detectReboot(conf.Root, newBt); !rebooted {
+		t.Error("the boot time should not be saved when posting the annotation fails")
+	}
+	if posted["batch"] != 1 {
+		t.Errorf("the annotation posted once should not be posted again on retry: %v", posted)
+	}
+
+	mu.Lock()
+	fail = false
+	mu.Unlock()
+	if err := reportRebootAt(context.Background(), app, newBt); err != nil {
+		t.Errorf("should not raise error: %s", err)
+	}
+	if _, rebooted, _ := detectReboot(conf.Root, newBt); rebooted {
+		t.Error("the boot time should be saved after the annotations are posted")
+	}
+	if posted["web"] != 1 {
+		t.Errorf("the annotation should be posted: %v", posted)
+	}
+}
+
+func TestRebootAnnotations(t *testing.T) {
+	host := &mkr.Host{
+		ID:    "3Bh1dK6RiTx",
+		Name:  "app01",
+		Roles: mkr.Roles{"web": {"app", "api"}, "batch": {"worker"}},
+	}
+	prev := time.Unix(1690000000, 0)
+	bt := time.Unix(1690086400, 0)
+	annotations := rebootAnnotations(host, prev, bt)
+	if len(annotations) != 2 {
+		t.Fatalf("annotations should be posted to 2 services but %d", len(annotations))
+	}
+	if a := annotations[0]; a.Service != "batch" || !reflect.DeepEqual(a.Roles, []string{"worker"}) {
+		t.Errorf("unexpected annotation: %+v", a)
+	}
+	if a := annotations[1]; a.Service != "web" || !reflect.DeepEqual(a.Roles, []string{"app", "api"}) {
+		t.Errorf("unexpected annotation: %+v", a)
+	}
+	if a := annotations[0]; a.From != bt.Unix() || a.To != bt.Unix() || a.Title != "Reboot: app01" {
+		t.Errorf("unexpected annotation: %+v", a)
+	}
+
+	if annotations := rebootAnnotations(&mkr.Host{Name: "app01"}, prev, bt); len(annotations) != 0 {
+		t.Errorf("annotations should not be posted without services: %+v", annotations)
+	}
+}
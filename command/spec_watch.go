@@ -0,0 +1,98 @@
+package command
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/mackerelio/mackerel-agent/config"
+	"github.com/mackerelio/mackerel-agent/spec"
+)
+
+// Changes of network interfaces usually come in bursts, such as a link
+// going up followed by addresses being assigned, so the host specs are
+// updated after no changes for specsUpdateDebounce, or specsUpdateMaxWait
+// after the first change of a burst which does not settle, e.g. a flapping link.
+var (
+	specsUpdateDebounce = 5 * time.Second
+	specsUpdateMaxWait  = 30 * time.Second
+)
+
+var hostnameCheckInterval = 30 * time.Second
+
+// watchHostSpecChanges returns the channel which receives a value when
+// network interfaces or the hostname change, debounced by specsUpdateDebounce
+// and specsUpdateMaxWait. The hostname is not watched if it is overridden by conf.
+func watchHostSpecChanges(ctx context.Context, conf *config.Config) <-chan struct{} {
+	changes := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case changes <- struct{}{}:
+		default:
+		}
+	}
+	if w, ok := interfaceGenerator().(spec.InterfaceWatcher); ok {
+		go func() {
+			if err := w.Watch(ctx, notify); err != nil && ctx.Err() == nil {
+				logger.Warningf("Stopped watching network interfaces, host specs are updated only periodically: %s", err)
+			}
+		}()
+	}
+	if conf.Hostname == "" {
+		go watchHostname(ctx, hostnameCheckInterval, notify)
+	}
+	return debounce(ctx, changes, specsUpdateDebounce, specsUpdateMaxWait)
+}
+
+// watchHostname calls notify when the hostname changes.
+// The hostname is polled since there is no portable way to be notified.
+func watchHostname(ctx context.Context, interval time.Duration, notify func()) {
+	hostname, _ := os.Hostname()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+		h, err := os.Hostname()
+		if err != nil {
+			logger.Debugf("Failed to get the hostname: %s", err)
+			continue
+		}
+		if h != hostname {
+			logger.Infof("The hostname changed from %q to %q", hostname, h)
+			hostname = h
+			notify()
+		}
+	}
+}
+
+// debounce returns the channel which receives a value when d passes
+// without receiving from in after receiving from in, or when maxWait
+// passes since the first value of the burst.
+func debounce(ctx context.Context, in <-chan struct{}, d, maxWait time.Duration) <-chan struct{} {
+	out := make(chan struct{}, 1)
+	go func() {
+		var timer, maxTimer <-chan time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-in:
+				timer = time.After(d)
+				if maxTimer == nil {
+					maxTimer = time.After(maxWait)
+				}
+				continue
+			case <-timer:
+			case <-maxTimer:
+			}
+			timer, maxTimer = nil, nil
+			select {
+			case out <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	return out
+}
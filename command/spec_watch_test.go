@@ -0,0 +1,71 @@
+package command
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDebounce(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	in := make(chan struct{})
+	out := debounce(ctx, in, 100*time.Millisecond, time.Second)
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		in <- struct{}{}
+		time.Sleep(50 * time.Millisecond)
+	}
+	select {
+	case <-out:
+		if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+			t.Errorf("should be notified after the burst but in %s", elapsed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("should be notified")
+	}
+
+	select {
+	case <-out:
+		t.Error("should be notified only once for a burst")
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestDebounce_maxWait(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	in := make(chan struct{})
+	out := debounce(ctx, in, 100*time.Millisecond, 300*time.Millisecond)
+
+	// A burst which never settles is notified maxWait after its first value.
+	start := time.Now()
+	var notified time.Duration
+	for i := 0; i < 20 && notified == 0; i++ {
+		in <- struct{}{}
+		select {
+		case <-out:
+			notified = time.Since(start)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	if notified < 300*time.Millisecond || notified > 600*time.Millisecond {
+		t.Errorf("should be notified about maxWait after the first value but in %s", notified)
+	}
+}
+
+func TestDebounce_cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	in := make(chan struct{}, 1)
+	out := debounce(ctx, in, 100*time.Millisecond, time.Second)
+	in <- struct{}{}
+	cancel()
+	select {
+	case <-out:
+		t.Error("should not be notified after canceled")
+	case <-time.After(200 * time.Millisecond):
+	}
+}
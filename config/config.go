@@ -5,7 +5,9 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"runtime"
 	"strings"
+	"text/template"
 	"time"
 	"unicode/utf8"
 
@@ -103,13 +105,31 @@ type Config struct {
 	Silent        bool
 	Diagnostic    bool          `toml:"diagnostic"`
 	DisplayName   string        `toml:"display_name"`
+	Hostname      string        `toml:"hostname"`
 	HostStatus    HostStatus    `toml:"host_status" conf:"parent"`
+	CPU           CPU           `toml:"cpu" conf:"parent"`
+	Pressure      Pressure      `toml:"pressure" conf:"parent"`
+	Memory        Memory        `toml:"memory" conf:"parent"`
+	Kernel        Kernel        `toml:"kernel" conf:"parent"`
+	TCP           TCP           `toml:"tcp" conf:"parent"`
+	Cgroup        Cgroup        `toml:"cgroup" conf:"parent"`
+	Systemd       Systemd       `toml:"systemd" conf:"parent"`
+	Docker        Docker        `toml:"docker" conf:"parent"`
+	Hwmon         Hwmon         `toml:"hwmon" conf:"parent"`
+	RAID          RAID          `toml:"raid" conf:"parent"`
+	TimeSync      TimeSync      `toml:"timesync" conf:"parent"`
+	Uptime        Uptime        `toml:"uptime" conf:"parent"`
 	Disks         Disks         `toml:"disks" conf:"parent"`
 	Filesystems   Filesystems   `toml:"filesystems" conf:"parent"`
 	Interfaces    Interfaces    `toml:"interfaces"  conf:"parent"`
 	HTTPProxy     string        `toml:"http_proxy"`
 	HTTPSProxy    string        `toml:"https_proxy"`
 	CloudPlatform CloudPlatform `toml:"cloud_platform"`
+	SelfCheck     SelfCheck     `toml:"self_check" conf:"parent"`
+
+	// Sampling configures sub-minute sampling for each family of built-in
+	// metrics, such as "cpu" or "interface".
+	Sampling map[string]*Sampling `toml:"sampling" conf:"parent"`
 
 	// This Plugin field is used to decode the toml file. After reading the
 	// configuration from file, this field is set to nil.
@@ -139,6 +159,15 @@ type PluginConfig struct {
 	ExcludePattern        *string       `toml:"exclude_pattern"`
 	Action                CommandConfig `toml:"action" conf:"parent"`
 	Memo                  string        `toml:"memo"`
+
+	// Built-in checks which are used instead of the command.
+	Certificate *CertificateCheck `toml:"certificate" conf:"parent"`
+	TCP         *TCPCheck         `toml:"tcp" conf:"parent"`
+	HTTP        *HTTPCheck        `toml:"http" conf:"parent"`
+	DNS         *DNSCheck         `toml:"dns" conf:"parent"`
+	RAID        *RAIDCheck        `toml:"raid" conf:"parent"`
+	Link        *LinkCheck        `toml:"link" conf:"parent"`
+	TimeSync    *TimeSyncCheck    `toml:"timesync" conf:"parent"`
 }
 
 // CommandConfig represents an executable command configuration.
@@ -258,6 +287,136 @@ type CheckPlugin struct {
 	PreventAlertAutoClose bool
 	Action                *Command
 	Memo                  string
+	Certificate           *CertificateCheck
+	TCP                   *TCPCheck
+	HTTP                  *HTTPCheck
+	DNS                   *DNSCheck
+	RAID                  *RAIDCheck
+	Link                  *LinkCheck
+	TimeSync              *TimeSyncCheck
+}
+
+// CertificateCheck represents the configuration of the built-in check
+// which monitors expiration of TLS certificates.
+type CertificateCheck struct {
+	Files        []string `toml:"files"`
+	Endpoints    []string `toml:"endpoints"`
+	ServerName   string   `toml:"server_name"`
+	CAFile       string   `toml:"ca_file"`
+	SkipVerify   bool     `toml:"skip_verify"`
+	WarningDays  *int     `toml:"warning_days"`
+	CriticalDays *int     `toml:"critical_days"`
+}
+
+// TCPCheck represents the configuration of the built-in check
+// which tries to connect to a TCP port.
+type TCPCheck struct {
+	Address string `toml:"address"`
+}
+
+// HTTPCheck represents the configuration of the built-in check
+// which sends an HTTP request and inspects the response.
+// Any 2xx status is expected if ExpectedStatus is zero.
+type HTTPCheck struct {
+	URL            string        `toml:"url"`
+	Method         string        `toml:"method"`
+	ExpectedStatus int           `toml:"expected_status"`
+	ExpectedBody   Regexpwrapper `toml:"expected_body"`
+	SkipVerify     bool          `toml:"skip_verify"`
+}
+
+// DNSCheck represents the configuration of the built-in check
+// which resolves a name. Server is "host" or "host:port" of the
+// name server, and the system resolver is used if it is empty.
+type DNSCheck struct {
+	Name              string   `toml:"name"`
+	Server            string   `toml:"server"`
+	ExpectedAddresses []string `toml:"expected_addresses"`
+}
+
+// RAIDCheck represents the configuration of the built-in check
+// which monitors Linux software RAID (md) arrays and ZFS pools.
+// All the arrays and pools found are checked if both are empty, except
+// inactive arrays such as the containers of external metadata (IMSM and DDF).
+type RAIDCheck struct {
+	Arrays []string `toml:"arrays"`
+	Pools  []string `toml:"pools"`
+}
+
+// LinkCheck represents the configuration of the built-in check
+// which monitors link states of network interfaces and the slaves of
+// bonding interfaces. All the bonds found are checked if both are empty.
+type LinkCheck struct {
+	Interfaces []string `toml:"interfaces"`
+	Bonds      []string `toml:"bonds"`
+}
+
+// TimeSyncCheck represents the configuration of the built-in check
+// which monitors that the system clock is synchronized (Linux only) and
+// its offset from the time of Mackerel API is within the thresholds.
+type TimeSyncCheck struct {
+	WarningSeconds  *float64 `toml:"warning_seconds"`
+	CriticalSeconds *float64 `toml:"critical_seconds"`
+}
+
+func (pconf *PluginConfig) countBuiltinChecks() int {
+	n := 0
+	if pconf.Certificate != nil {
+		n++
+	}
+	if pconf.TCP != nil {
+		n++
+	}
+	if pconf.HTTP != nil {
+		n++
+	}
+	if pconf.DNS != nil {
+		n++
+	}
+	if pconf.RAID != nil {
+		n++
+	}
+	if pconf.Link != nil {
+		n++
+	}
+	if pconf.TimeSync != nil {
+		n++
+	}
+	return n
+}
+
+func (pconf *PluginConfig) validateBuiltinCheck(name string) error {
+	if pconf.Certificate != nil && len(pconf.Certificate.Files) == 0 && len(pconf.Certificate.Endpoints) == 0 {
+		return fmt.Errorf("'plugin.checks.%s.certificate' should have at least one of files or endpoints", name)
+	}
+	if pconf.TCP != nil && pconf.TCP.Address == "" {
+		return fmt.Errorf("'plugin.checks.%s.tcp.address' is required", name)
+	}
+	if pconf.HTTP != nil && pconf.HTTP.URL == "" {
+		return fmt.Errorf("'plugin.checks.%s.http.url' is required", name)
+	}
+	if pconf.DNS != nil && pconf.DNS.Name == "" {
+		return fmt.Errorf("'plugin.checks.%s.dns.name' is required", name)
+	}
+	if pconf.TimeSync != nil {
+		if err := pconf.TimeSync.validate(); err != nil {
+			return fmt.Errorf("'plugin.checks.%s.timesync': %s", name, err)
+		}
+	}
+	return nil
+}
+
+func (c *TimeSyncCheck) validate() error {
+	if c.WarningSeconds != nil && *c.WarningSeconds <= 0 {
+		return fmt.Errorf("warning_seconds should be positive: %v", *c.WarningSeconds)
+	}
+	if c.CriticalSeconds != nil && *c.CriticalSeconds <= 0 {
+		return fmt.Errorf("critical_seconds should be positive: %v", *c.CriticalSeconds)
+	}
+	if c.WarningSeconds != nil && c.CriticalSeconds != nil && *c.WarningSeconds > *c.CriticalSeconds {
+		return fmt.Errorf("warning_seconds should not be greater than critical_seconds: %v > %v", *c.WarningSeconds, *c.CriticalSeconds)
+	}
+	return nil
 }
 
 func (pconf *PluginConfig) buildCheckPlugin(name string) (*CheckPlugin, error) {
@@ -265,6 +424,16 @@ func (pconf *PluginConfig) buildCheckPlugin(name string) (*CheckPlugin, error) {
 	if err != nil {
 		return nil, err
 	}
+	if n := pconf.countBuiltinChecks(); n > 1 || (n > 0 && cmd != nil) {
+		return nil, fmt.Errorf("'plugin.checks.%s' should have only one of command, certificate, tcp, http, dns, raid, link and timesync", name)
+	} else if n > 0 {
+		if err := pconf.validateBuiltinCheck(name); err != nil {
+			return nil, err
+		}
+		// Built-in checks use only the timeout of the command configuration.
+		cmd = &Command{}
+		cmd.TimeoutDuration = time.Duration(pconf.TimeoutSeconds * int64(time.Second))
+	}
 	if cmd == nil {
 		return nil, fmt.Errorf("failed to parse plugin command. A configuration value of `command` should be string or string slice, but %T", pconf.Raw)
 	}
@@ -297,6 +466,13 @@ func (pconf *PluginConfig) buildCheckPlugin(name string) (*CheckPlugin, error) {
 		PreventAlertAutoClose: pconf.PreventAlertAutoClose,
 		Action:                action,
 		Memo:                  pconf.Memo,
+		Certificate:           pconf.Certificate,
+		TCP:                   pconf.TCP,
+		HTTP:                  pconf.HTTP,
+		DNS:                   pconf.DNS,
+		RAID:                  pconf.RAID,
+		Link:                  pconf.Link,
+		TimeSync:              pconf.TimeSync,
 	}
 	if plugin.MaxCheckAttempts != nil && *plugin.MaxCheckAttempts > 1 && plugin.PreventAlertAutoClose {
 		*plugin.MaxCheckAttempts = 1
@@ -365,26 +541,174 @@ func (cc CommandConfig) parse() (cmd *Command, err error) {
 // PostMetricsInterval XXX
 var PostMetricsInterval = 1 * time.Minute
 
+// ParseDisplayName parses display_name as a template of text/template.
+// The template can use `join` function in addition to the builtin ones.
+// Referring to a missing key of a map, e.g. an undefined environment variable,
+// fails on execution.
+func ParseDisplayName(displayName string) (*template.Template, error) {
+	tmpl, err := template.New("display_name").Funcs(template.FuncMap{
+		"join": strings.Join,
+	}).Option("missingkey=error").Parse(displayName)
+	if err != nil {
+		return nil, errors.Wrap(err, "display_name")
+	}
+	return tmpl, nil
+}
+
 // HostStatus configure host status on agent start/stop
 type HostStatus struct {
 	OnStart string `toml:"on_start"`
 	OnStop  string `toml:"on_stop"`
 }
 
+// CPU configure CPU related settings
+type CPU struct {
+	PerCore bool `toml:"per_core"`
+}
+
+// Pressure configure Pressure Stall Information related settings
+type Pressure struct {
+	Enabled bool `toml:"enabled"`
+}
+
+// Memory configure memory related settings
+type Memory struct {
+	ExtendedMetrics bool `toml:"extended_metrics"`
+}
+
+// Kernel configure kernel activity related settings
+type Kernel struct {
+	Enabled bool `toml:"enabled"`
+}
+
+// TCP configure TCP related settings
+type TCP struct {
+	Enabled bool `toml:"enabled"`
+}
+
+// Cgroup configure whether the CPU and memory metrics describe the host or
+// the cgroup v2 which the agent belongs to, e.g. when running in a container.
+type Cgroup struct {
+	View string `toml:"view"`
+}
+
+// The views of the CPU and memory metrics
+const (
+	CgroupViewHost   = "host"
+	CgroupViewCgroup = "cgroup"
+)
+
+func (conf *Config) validateCgroup() error {
+	switch conf.Cgroup.View {
+	case "", CgroupViewHost, CgroupViewCgroup:
+		return nil
+	}
+	return fmt.Errorf("cgroup.view should be %q or %q: %q", CgroupViewHost, CgroupViewCgroup, conf.Cgroup.View)
+}
+
+// Systemd configure the metrics of each systemd unit. The units whose names
+// match Include (if set) and do not match Exclude are collected.
+type Systemd struct {
+	Enabled bool          `toml:"enabled"`
+	Include Regexpwrapper `toml:"include"`
+	Exclude Regexpwrapper `toml:"exclude"`
+}
+
+// Docker configure the metrics and the metadata of Docker containers
+// collected via the Engine API on the Unix socket (Linux and macOS only)
+type Docker struct {
+	Enabled bool   `toml:"enabled"`
+	Socket  string `toml:"socket"`
+}
+
+// Hwmon configure hardware monitoring related settings
+type Hwmon struct {
+	Enabled bool `toml:"enabled"`
+}
+
+// RAID configure software RAID and ZFS pool related settings
+type RAID struct {
+	Enabled bool `toml:"enabled"`
+}
+
+// TimeSync configure time synchronization related settings
+type TimeSync struct {
+	Enabled   bool `toml:"enabled"`
+	APIOffset bool `toml:"api_offset"`
+}
+
+// Uptime configure uptime and reboot detection related settings
+type Uptime struct {
+	Enabled bool `toml:"enabled"`
+}
+
 // Disks configure disks related settings
 type Disks struct {
-	Ignore Regexpwrapper `toml:"ignore"`
+	Ignore          Regexpwrapper `toml:"ignore"`
+	ExtendedMetrics bool          `toml:"extended_metrics"`
 }
 
 // Filesystems configure filesystem related settings
 type Filesystems struct {
-	Ignore        Regexpwrapper `toml:"ignore"`
-	UseMountpoint bool          `toml:"use_mountpoint"`
+	Ignore          Regexpwrapper `toml:"ignore"`
+	UseMountpoint   bool          `toml:"use_mountpoint"`
+	ExtendedMetrics bool          `toml:"extended_metrics"`
+	// IgnoreFSTypes overrides the filesystem types which are not collected
+	// on Linux, network and pseudo filesystems by default.
+	IgnoreFSTypes []string `toml:"ignore_fs_types"`
+}
+
+// SelfCheck configures the built-in check monitoring the agent itself.
+// It reports CRITICAL when the queue of metric values waiting for being
+// posted has kept growing without draining for PostQueueGrowthMinutes,
+// and WARNING when a plugin has failed PluginFailureCount times in a row,
+// when creating graph definitions has failed, or when a check has not
+// completed for a long time.
+type SelfCheck struct {
+	Enabled                bool `toml:"enabled"`
+	PostQueueGrowthMinutes int  `toml:"post_queue_growth_minutes"`
+	PluginFailureCount     int  `toml:"plugin_failure_count"`
+}
+
+// Sampling configures a family of built-in metrics to be sampled every
+// IntervalSeconds. The agent posts the average of the samples once per
+// minute, and also the aggregations listed in Aggregations as custom
+// metrics suffixed with their names.
+type Sampling struct {
+	IntervalSeconds int      `toml:"interval_seconds"`
+	Aggregations    []string `toml:"aggregations"`
+}
+
+// SamplingFamilies are the families of built-in metrics which can be sampled.
+var SamplingFamilies = []string{"loadavg", "cpu", "memory", "interface", "disk", "filesystem"}
+
+// SamplingAggregations are the available aggregations of samples.
+var SamplingAggregations = []string{"max", "min", "p95"}
+
+func (conf *Config) validateSampling() error {
+	for family, s := range conf.Sampling {
+		if index(SamplingFamilies, family) == -1 {
+			return fmt.Errorf("sampling.%s: unknown family of metrics", family)
+		}
+		if s.IntervalSeconds <= 0 || int(PostMetricsInterval.Seconds())%s.IntervalSeconds != 0 {
+			return fmt.Errorf("sampling.%s: interval_seconds should be a divisor of %d", family, int(PostMetricsInterval.Seconds()))
+		}
+		for _, a := range s.Aggregations {
+			if index(SamplingAggregations, a) == -1 {
+				return fmt.Errorf("sampling.%s: unknown aggregation %q", family, a)
+			}
+		}
+	}
+	return nil
 }
 
 // Interfaces configure intefaces related settings
 type Interfaces struct {
-	Ignore Regexpwrapper `toml:"ignore"`
+	Ignore          Regexpwrapper `toml:"ignore"`
+	ExtendedMetrics bool          `toml:"extended_metrics"`
+	// LinkMetadata posts link states of interfaces and bonding slaves as
+	// the host metadata "interfaces" since the host specs have no fields for them.
+	LinkMetadata bool `toml:"link_metadata"`
 }
 
 // Regexpwrapper is a wrapper type for marshalling string
@@ -504,9 +828,31 @@ func loadConfigFile(file string) (*Config, error) {
 		}
 	}
 
+	if err := config.validateSampling(); err != nil {
+		return nil, err
+	}
+	if err := config.validateCgroup(); err != nil {
+		return nil, err
+	}
+	if err := config.validateDocker(); err != nil {
+		return nil, err
+	}
+
+	if _, err := ParseDisplayName(config.DisplayName); err != nil {
+		return nil, err
+	}
+
 	return config, nil
 }
 
+// The Engine API on Windows listens on a named pipe instead of a Unix socket.
+func (conf *Config) validateDocker() error {
+	if conf.Docker.Enabled && runtime.GOOS == "windows" {
+		return fmt.Errorf("docker: not supported on Windows")
+	}
+	return nil
+}
+
 func includeConfigFile(config *Config, include string) error {
 	files, err := filepath.Glob(include)
 	if err != nil {
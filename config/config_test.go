@@ -164,6 +164,126 @@ func TestLoadConfigWithMountPoint(t *testing.T) {
 	}
 }
 
+var sampleConfigWithExtendedMetrics = `
+apikey = "abcde"
+
+[cpu]
+per_core = true
+
+[pressure]
+enabled = true
+
+[memory]
+extended_metrics = true
+
+[kernel]
+enabled = true
+
+[tcp]
+enabled = true
+
+[hwmon]
+enabled = true
+
+[raid]
+enabled = true
+
+[timesync]
+enabled = true
+api_offset = true
+
+[uptime]
+enabled = true
+
+[docker]
+enabled = true
+socket = "/run/podman/podman.sock"
+
+[systemd]
+enabled = true
+include = "\\.service$"
+exclude = "^systemd-"
+
+[disks]
+extended_metrics = true
+
+[interfaces]
+extended_metrics = true
+link_metadata = true
+
+[filesystems]
+extended_metrics = true
+ignore_fs_types = ["proc", "sysfs"]
+`
+
+func TestLoadConfigWithExtendedMetrics(t *testing.T) {
+	tmpFile, err := newTempFileWithContent(sampleConfigWithExtendedMetrics)
+	if err != nil {
+		t.Errorf("should not raise error: %v", err)
+	}
+	t.Cleanup(func() { os.Remove(tmpFile.Name()) })
+
+	config, err := LoadConfig(tmpFile.Name())
+	if err != nil {
+		t.Errorf("should not raise error: %v", err)
+	}
+
+	if config.CPU.PerCore != true {
+		t.Error("should be true (config value should be used)")
+	}
+	if config.Pressure.Enabled != true {
+		t.Error("should be true (config value should be used)")
+	}
+	if config.Memory.ExtendedMetrics != true {
+		t.Error("should be true (config value should be used)")
+	}
+	if config.Kernel.Enabled != true {
+		t.Error("should be true (config value should be used)")
+	}
+	if config.TCP.Enabled != true {
+		t.Error("should be true (config value should be used)")
+	}
+	if config.Hwmon.Enabled != true {
+		t.Error("should be true (config value should be used)")
+	}
+	if config.RAID.Enabled != true {
+		t.Error("should be true (config value should be used)")
+	}
+	if config.Uptime.Enabled != true {
+		t.Error("should be true (config value should be used)")
+	}
+	if config.TimeSync.Enabled != true || config.TimeSync.APIOffset != true {
+		t.Errorf("unexpected timesync config: %+v", config.TimeSync)
+	}
+	if config.Docker.Enabled != true || config.Docker.Socket != "/run/podman/podman.sock" {
+		t.Errorf("unexpected docker config: %+v", config.Docker)
+	}
+	if config.Systemd.Enabled != true {
+		t.Error("should be true (config value should be used)")
+	}
+	if !config.Systemd.Include.MatchString("nginx.service") || config.Systemd.Include.MatchString("user.slice") {
+		t.Errorf("unexpected systemd.include: %s", config.Systemd.Include)
+	}
+	if !config.Systemd.Exclude.MatchString("systemd-journald.service") {
+		t.Errorf("unexpected systemd.exclude: %s", config.Systemd.Exclude)
+	}
+	if config.Disks.ExtendedMetrics != true {
+		t.Error("should be true (config value should be used)")
+	}
+	if config.Interfaces.ExtendedMetrics != true {
+		t.Error("should be true (config value should be used)")
+	}
+	if config.Interfaces.LinkMetadata != true {
+		t.Error("link_metadata should be true (config value should be used)")
+	}
+	if config.Filesystems.ExtendedMetrics != true {
+		t.Error("should be true (config value should be used)")
+	}
+	if !reflect.DeepEqual(config.Filesystems.IgnoreFSTypes, []string{"proc", "sysfs"}) {
+		t.Errorf("unexpected ignore_fs_types: %v", config.Filesystems.IgnoreFSTypes)
+	}
+}
+
 var sampleConfigWithInvalidIgnoreRegexp = `
 apikey = "abcde"
 display_name = "fghij"
@@ -302,6 +422,392 @@ func TestLoadConfigWithTooLargeCheckMemo(t *testing.T) {
 	}
 }
 
+var sampleConfigWithCertificateCheck = `
+apikey = "abcde"
+
+[plugin.checks.cert]
+certificate = { endpoints = ["example.com:443"], warning_days = 20, critical_days = 5 }
+timeout_seconds = 3
+
+[plugin.checks.cert2.certificate]
+files = ["/etc/ssl/server.pem"]
+skip_verify = true
+`
+
+func TestLoadConfigWithCertificateCheck(t *testing.T) {
+	tmpFile, err := newTempFileWithContent(sampleConfigWithCertificateCheck)
+	if err != nil {
+		t.Errorf("should not raise error: %v", err)
+	}
+	t.Cleanup(func() { os.Remove(tmpFile.Name()) })
+
+	config, err := LoadConfig(tmpFile.Name())
+	if err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+
+	cert := config.CheckPlugins["cert"]
+	if cert.Certificate == nil {
+		t.Fatal("check should have certificate configuration")
+	}
+	if !reflect.DeepEqual(cert.Certificate.Endpoints, []string{"example.com:443"}) {
+		t.Errorf("unexpected endpoints: %v", cert.Certificate.Endpoints)
+	}
+	if *cert.Certificate.WarningDays != 20 || *cert.Certificate.CriticalDays != 5 {
+		t.Errorf("unexpected thresholds: %d, %d", *cert.Certificate.WarningDays, *cert.Certificate.CriticalDays)
+	}
+	if cert.Command.TimeoutDuration != 3*time.Second {
+		t.Errorf("timeout should be 3s but %v", cert.Command.TimeoutDuration)
+	}
+
+	cert2 := config.CheckPlugins["cert2"]
+	if !reflect.DeepEqual(cert2.Certificate.Files, []string{"/etc/ssl/server.pem"}) {
+		t.Errorf("unexpected files: %v", cert2.Certificate.Files)
+	}
+	if !cert2.Certificate.SkipVerify {
+		t.Error("skip_verify should be true")
+	}
+	if cert2.Certificate.WarningDays != nil {
+		t.Errorf("warning_days should be nil but %v", *cert2.Certificate.WarningDays)
+	}
+}
+
+var sampleConfigWithProbeChecks = `
+apikey = "abcde"
+
+[plugin.checks.postgres]
+tcp = { address = "localhost:5432" }
+
+[plugin.checks.healthz]
+http = { url = "http://localhost:8080/healthz", expected_status = 204, expected_body = "^ok" }
+timeout_seconds = 2
+
+[plugin.checks.resolve]
+dns = { name = "example.com", server = "192.0.2.53", expected_addresses = ["192.0.2.1"] }
+
+[plugin.checks.raid]
+raid = { arrays = ["md0", "md1"], pools = ["tank"] }
+
+[plugin.checks.link]
+link = { interfaces = ["eth0"], bonds = ["bond0"] }
+
+[plugin.checks.clock]
+timesync = { warning_seconds = 1.5 }
+`
+
+func TestLoadConfigWithProbeChecks(t *testing.T) {
+	tmpFile, err := newTempFileWithContent(sampleConfigWithProbeChecks)
+	if err != nil {
+		t.Errorf("should not raise error: %v", err)
+	}
+	t.Cleanup(func() { os.Remove(tmpFile.Name()) })
+
+	config, err := LoadConfig(tmpFile.Name())
+	if err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+
+	if tcp := config.CheckPlugins["postgres"].TCP; tcp == nil || tcp.Address != "localhost:5432" {
+		t.Errorf("unexpected tcp check: %+v", tcp)
+	}
+	healthz := config.CheckPlugins["healthz"]
+	if healthz.HTTP.URL != "http://localhost:8080/healthz" || healthz.HTTP.ExpectedStatus != 204 {
+		t.Errorf("unexpected http check: %+v", healthz.HTTP)
+	}
+	if healthz.HTTP.ExpectedBody.String() != "^ok" {
+		t.Errorf("unexpected expected_body: %v", healthz.HTTP.ExpectedBody)
+	}
+	if healthz.Command.TimeoutDuration != 2*time.Second {
+		t.Errorf("timeout should be 2s but %v", healthz.Command.TimeoutDuration)
+	}
+	dns := config.CheckPlugins["resolve"].DNS
+	if dns.Name != "example.com" || dns.Server != "192.0.2.53" || !reflect.DeepEqual(dns.ExpectedAddresses, []string{"192.0.2.1"}) {
+		t.Errorf("unexpected dns check: %+v", dns)
+	}
+	raid := config.CheckPlugins["raid"].RAID
+	if raid == nil || !reflect.DeepEqual(raid.Arrays, []string{"md0", "md1"}) || !reflect.DeepEqual(raid.Pools, []string{"tank"}) {
+		t.Errorf("unexpected raid check: %+v", raid)
+	}
+	link := config.CheckPlugins["link"].Link
+	if link == nil || !reflect.DeepEqual(link.Interfaces, []string{"eth0"}) || !reflect.DeepEqual(link.Bonds, []string{"bond0"}) {
+		t.Errorf("unexpected link check: %+v", link)
+	}
+	timesync := config.CheckPlugins["clock"].TimeSync
+	if timesync == nil || timesync.WarningSeconds == nil || *timesync.WarningSeconds != 1.5 || timesync.CriticalSeconds != nil {
+		t.Errorf("unexpected timesync check: %+v", timesync)
+	}
+}
+
+var sampleConfigWithSelfCheck = `
+apikey = "abcde"
+
+[self_check]
+enabled = true
+post_queue_growth_minutes = 30
+`
+
+func TestLoadConfigWithSelfCheck(t *testing.T) {
+	tmpFile, err := newTempFileWithContent(sampleConfigWithSelfCheck)
+	if err != nil {
+		t.Errorf("should not raise error: %v", err)
+	}
+	t.Cleanup(func() { os.Remove(tmpFile.Name()) })
+
+	config, err := LoadConfig(tmpFile.Name())
+	if err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+	if !config.SelfCheck.Enabled {
+		t.Error("self_check should be enabled")
+	}
+	if config.SelfCheck.PostQueueGrowthMinutes != 30 {
+		t.Errorf("post_queue_growth_minutes should be 30 but %d", config.SelfCheck.PostQueueGrowthMinutes)
+	}
+	if config.SelfCheck.PluginFailureCount != 0 {
+		t.Errorf("plugin_failure_count should be 0 but %d", config.SelfCheck.PluginFailureCount)
+	}
+}
+
+func TestLoadConfigWithInvalidBuiltinChecks(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name: "command and tcp",
+			content: `
+[plugin.checks.dup]
+command = "check-tcp"
+tcp = { address = "localhost:5432" }
+`,
+			want: "should have only one of",
+		},
+		{
+			name: "tcp and http",
+			content: `
+[plugin.checks.dup]
+tcp = { address = "localhost:5432" }
+http = { url = "http://localhost/" }
+`,
+			want: "should have only one of",
+		},
+		{
+			name: "empty address",
+			content: `
+[plugin.checks.empty]
+tcp = {}
+`,
+			want: "'plugin.checks.empty.tcp.address' is required",
+		},
+		{
+			name: "negative timesync threshold",
+			content: `
+[plugin.checks.clock]
+timesync = { warning_seconds = -1 }
+`,
+			want: "'plugin.checks.clock.timesync': warning_seconds should be positive",
+		},
+		{
+			name: "zero timesync threshold",
+			content: `
+[plugin.checks.clock]
+timesync = { critical_seconds = 0 }
+`,
+			want: "'plugin.checks.clock.timesync': critical_seconds should be positive",
+		},
+		{
+			name: "timesync warning greater than critical",
+			content: `
+[plugin.checks.clock]
+timesync = { warning_seconds = 10, critical_seconds = 3 }
+`,
+			want: "warning_seconds should not be greater than critical_seconds",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpFile, err := newTempFileWithContent(tt.content)
+			if err != nil {
+				t.Errorf("should not raise error: %v", err)
+			}
+			t.Cleanup(func() { os.Remove(tmpFile.Name()) })
+
+			_, err = LoadConfig(tmpFile.Name())
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("should raise error containing %q: %v", tt.want, err)
+			}
+		})
+	}
+}
+
+var sampleConfigWithSampling = `
+apikey = "abcde"
+
+[sampling.cpu]
+interval_seconds = 10
+aggregations = ["max", "p95"]
+
+[sampling.interface]
+interval_seconds = 15
+`
+
+func TestLoadConfigWithSampling(t *testing.T) {
+	tmpFile, err := newTempFileWithContent(sampleConfigWithSampling)
+	if err != nil {
+		t.Errorf("should not raise error: %v", err)
+	}
+	t.Cleanup(func() { os.Remove(tmpFile.Name()) })
+
+	config, err := LoadConfig(tmpFile.Name())
+	if err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+	expected := map[string]*Sampling{
+		"cpu":       {IntervalSeconds: 10, Aggregations: []string{"max", "p95"}},
+		"interface": {IntervalSeconds: 15},
+	}
+	if !reflect.DeepEqual(config.Sampling, expected) {
+		t.Errorf("sampling should be %+v but %+v", expected, config.Sampling)
+	}
+}
+
+func TestLoadConfigWithInvalidSampling(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name: "unknown family",
+			content: `
+[sampling.gpu]
+interval_seconds = 10
+`,
+			want: "sampling.gpu: unknown family",
+		},
+		{
+			name: "not a divisor",
+			content: `
+[sampling.cpu]
+interval_seconds = 7
+`,
+			want: "interval_seconds should be a divisor of 60",
+		},
+		{
+			name: "unknown aggregation",
+			content: `
+[sampling.cpu]
+interval_seconds = 10
+aggregations = ["median"]
+`,
+			want: `unknown aggregation "median"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpFile, err := newTempFileWithContent(tt.content)
+			if err != nil {
+				t.Errorf("should not raise error: %v", err)
+			}
+			t.Cleanup(func() { os.Remove(tmpFile.Name()) })
+
+			_, err = LoadConfig(tmpFile.Name())
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("should raise error containing %q: %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestLoadConfigWithCgroupView(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantErr bool
+	}{
+		{name: "default", content: ``, want: ""},
+		{name: "host", content: "[cgroup]\nview = \"host\"\n", want: CgroupViewHost},
+		{name: "cgroup", content: "[cgroup]\nview = \"cgroup\"\n", want: CgroupViewCgroup},
+		{name: "invalid", content: "[cgroup]\nview = \"container\"\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpFile, err := newTempFileWithContent(tt.content)
+			if err != nil {
+				t.Errorf("should not raise error: %v", err)
+			}
+			t.Cleanup(func() { os.Remove(tmpFile.Name()) })
+
+			config, err := LoadConfig(tmpFile.Name())
+			if tt.wantErr {
+				if err == nil {
+					t.Error("should raise error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("should not raise error: %v", err)
+			}
+			if config.Cgroup.View != tt.want {
+				t.Errorf("cgroup.view should be %q but %q", tt.want, config.Cgroup.View)
+			}
+		})
+	}
+}
+
+func TestLoadConfigWithHostnameAndDisplayName(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		hostname    string
+		displayName string
+		wantErr     bool
+	}{
+		{name: "default", content: ``},
+		{
+			name:        "override",
+			content:     "hostname = \"app01.example.com\"\ndisplay_name = \"app01\"\n",
+			hostname:    "app01.example.com",
+			displayName: "app01",
+		},
+		{
+			name:        "template",
+			content:     "display_name = '{{.Env.SERVICE}}-{{index .Cloud.Metadata \"instance-id\"}}'\n",
+			displayName: `{{.Env.SERVICE}}-{{index .Cloud.Metadata "instance-id"}}`,
+		},
+		{name: "invalid template", content: "display_name = '{{.Hostname'\n", wantErr: true},
+		{name: "undefined function", content: "display_name = '{{upper .Hostname}}'\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpFile, err := newTempFileWithContent(tt.content)
+			if err != nil {
+				t.Errorf("should not raise error: %v", err)
+			}
+			t.Cleanup(func() { os.Remove(tmpFile.Name()) })
+
+			config, err := LoadConfig(tmpFile.Name())
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "display_name") {
+					t.Errorf("should raise error of display_name: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("should not raise error: %v", err)
+			}
+			if config.Hostname != tt.hostname {
+				t.Errorf("hostname should be %q but %q", tt.hostname, config.Hostname)
+			}
+			if config.DisplayName != tt.displayName {
+				t.Errorf("display_name should be %q but %q", tt.displayName, config.DisplayName)
+			}
+		})
+	}
+}
+
 var sampleConfigWithInvalidMetadataCommand = `
 apikey = "abcde"
 
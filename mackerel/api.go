@@ -3,7 +3,9 @@ package mackerel
 import (
 	"errors"
 	"fmt"
+	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/mackerelio/golib/logging"
 	mkr "github.com/mackerelio/mackerel-client-go"
@@ -14,6 +16,10 @@ var logger = logging.GetLogger("api")
 // API is the main interface of Mackerel API.
 type API struct {
 	*mkr.Client
+
+	// RecordClockOffset is called with the offset of the local clock
+	// estimated from the Date header of each response if it is set.
+	RecordClockOffset func(offset time.Duration)
 }
 
 // IsNetworkError returns true if err is url.Error caused by net/http
@@ -62,7 +68,37 @@ func NewAPI(rawurl string, apiKey string, verbose bool) (*API, error) {
 		return nil, err
 	}
 	c.PrioritizedLogger = logger
-	return &API{Client: c}, nil
+	api := &API{Client: c}
+	c.HTTPClient.Transport = &clockOffsetTransport{base: http.DefaultTransport, api: api}
+	return api, nil
+}
+
+// clockOffsetTransport passes the offset of the local clock from the Date
+// header of the responses to RecordClockOffset of api.
+type clockOffsetTransport struct {
+	base http.RoundTripper
+	api  *API
+}
+
+func (t *clockOffsetTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || t.api.RecordClockOffset == nil {
+		return resp, err
+	}
+	if date, err := http.ParseTime(resp.Header.Get("Date")); err == nil {
+		t.api.RecordClockOffset(estimateClockOffset(start, time.Now(), date))
+	}
+	return resp, nil
+}
+
+// estimateClockOffset estimates the offset of the local clock, assuming that
+// the server generated the Date header in the middle of the request. Since
+// the header is truncated to seconds, the error can be up to 0.5 seconds
+// plus the half of the round trip time.
+func estimateClockOffset(start, end, date time.Time) time.Duration {
+	local := start.Add(end.Sub(start) / 2)
+	return local.Sub(date.Add(500 * time.Millisecond))
 }
 
 // FindHostByCustomIdentifier find the host by the custom identifier
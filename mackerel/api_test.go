@@ -8,6 +8,7 @@ import (
 	"net/url"
 	"reflect"
 	"testing"
+	"time"
 
 	mkr "github.com/mackerelio/mackerel-client-go"
 	"github.com/pkg/errors"
@@ -216,3 +217,37 @@ func TestServerError(t *testing.T) {
 		}
 	}
 }
+
+func TestRecordClockOffset(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		// the clock of the server is 1 hour behind
+		res.Header().Set("Date", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat))
+		res.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(res, `{"hosts":[]}`)
+	}))
+	defer ts.Close()
+
+	api, _ := NewAPI(ts.URL, "dummy-key", false)
+	var offset time.Duration
+	api.RecordClockOffset = func(d time.Duration) {
+		offset = d
+	}
+	if _, err := api.FindHosts(&mkr.FindHostsParam{}); err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+	if offset < time.Hour-2*time.Second || offset > time.Hour+2*time.Second {
+		t.Errorf("offset should be about 1 hour but %s", offset)
+	}
+}
+
+func TestEstimateClockOffset(t *testing.T) {
+	date := time.Date(2023, 7, 22, 4, 26, 40, 0, time.UTC)
+	start := date.Add(300 * time.Millisecond)
+	end := start.Add(400 * time.Millisecond)
+	if d := estimateClockOffset(start, end, date); d != 0 {
+		t.Errorf("offset should be 0 but %s", d)
+	}
+	if d := estimateClockOffset(start.Add(3*time.Second), end.Add(3*time.Second), date); d != 3*time.Second {
+		t.Errorf("offset should be 3s but %s", d)
+	}
+}
@@ -20,10 +20,17 @@ type Generator struct {
 	Config       *config.MetadataPlugin
 	Cachefile    string
 	PrevMetadata any
+
+	// Fetcher generates the metadata instead of the command of Config
+	// for the built-in metadata, such as the inventory of Docker containers.
+	Fetcher func() (any, error)
 }
 
 // Fetch invokes the command and returns the result
 func (g *Generator) Fetch() (any, error) {
+	if g.Fetcher != nil {
+		return g.fetchBuiltin()
+	}
 	message, stderr, exitCode, err := g.Config.Command.Run()
 
 	if err != nil {
@@ -48,6 +55,25 @@ func (g *Generator) Fetch() (any, error) {
 	return metadata, nil
 }
 
+// fetchBuiltin normalizes the result of Fetcher via JSON to compare it
+// with the metadata loaded from the cache file.
+func (g *Generator) fetchBuiltin() (any, error) {
+	v, err := g.Fetcher()
+	if err != nil {
+		logger.Warningf("Error occurred while fetching a built-in metadata %q: %v", g.Name, err)
+		return nil, err
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal the metadata to json: %v", err)
+	}
+	var metadata any
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
 // IsChanged returns whether the metadata has been changed or not
 func (g *Generator) IsChanged(metadata any) bool {
 	if g.PrevMetadata == nil {
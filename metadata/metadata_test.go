@@ -2,7 +2,9 @@ package metadata
 
 import (
 	"encoding/json"
+	"errors"
 	"path/filepath"
+	"reflect"
 	"strconv"
 	"testing"
 	"time"
@@ -90,6 +92,45 @@ func TestMetadataGeneratorFetch(t *testing.T) {
 	}
 }
 
+func TestMetadataGeneratorFetchBuiltin(t *testing.T) {
+	type item struct {
+		Name string `json:"name"`
+	}
+	g := Generator{
+		Name:      "builtin",
+		Config:    &config.MetadataPlugin{},
+		Cachefile: filepath.Join("testdata", ".mackerel-metadata-test-builtin"),
+		Fetcher: func() (any, error) {
+			return struct {
+				Items []item `json:"items"`
+			}{Items: []item{{Name: "foo"}}}, nil
+		},
+	}
+	metadata, err := g.Fetch()
+	if err != nil {
+		t.Fatalf("error occurred unexpectedly: %s", err)
+	}
+	expect := map[string]any{"items": []any{map[string]any{"name": "foo"}}}
+	if !reflect.DeepEqual(metadata, expect) {
+		t.Errorf("metadata should be %v but got %v", expect, metadata)
+	}
+
+	// The metadata should be compared with the one loaded from the cache file.
+	if err := g.Save(metadata); err != nil {
+		t.Fatalf("Error should not occur in Save() but got: %s", err)
+	}
+	t.Cleanup(func() { g.Clear() })
+	g.PrevMetadata = nil
+	if metadata, _ := g.Fetch(); g.IsChanged(metadata) {
+		t.Errorf("IsChanged() should return false for the same metadata")
+	}
+
+	g.Fetcher = func() (any, error) { return nil, errors.New("unavailable") }
+	if _, err := g.Fetch(); err == nil {
+		t.Error("error should occur")
+	}
+}
+
 func TestMetadataGeneratorSaveIsChanged(t *testing.T) {
 	tests := []struct {
 		prevmetadata string
@@ -9,11 +9,13 @@ import (
 // AgentGenerator is generator of metrics
 // about the running agent itself
 type AgentGenerator struct {
+	Stats *AgentStats
 }
 
 var memStats = new(runtime.MemStats)
 
-// Generate generates the memory usage of the running agent itself
+// Generate generates the memory usage of the running agent itself,
+// and the diagnostic metrics recorded in Stats
 func (g *AgentGenerator) Generate() (Values, error) {
 	runtime.ReadMemStats(memStats)
 
@@ -24,6 +26,9 @@ func (g *AgentGenerator) Generate() (Values, error) {
 		"custom.agent.memory.heapSys":        float64(memStats.HeapSys),
 		"custom.agent.runtime.goroutine_num": float64(runtime.NumGoroutine()),
 	}
+	if g.Stats != nil {
+		ret = merge(ret, g.Stats.values())
+	}
 
 	return ret, nil
 }
@@ -36,11 +41,11 @@ func (g *AgentGenerator) CustomIdentifier() *string {
 // PrepareGraphDefs for PluginGenerator interface
 func (g *AgentGenerator) PrepareGraphDefs() ([]*mkr.GraphDefsParam, error) {
 	meta := &pluginMeta{
-		Graphs: map[string]customGraphDef{
+		Graphs: map[string]CustomGraphDef{
 			"agent.memory": {
 				Label: "Agent Memory",
 				Unit:  "bytes",
-				Metrics: []customGraphMetricDef{
+				Metrics: []CustomGraphMetricDef{
 					{Name: "alloc", Label: "Alloc"},
 					{Name: "sys", Label: "Sys"},
 					{Name: "heapAlloc", Label: "Heap Alloc"},
@@ -50,10 +55,54 @@ func (g *AgentGenerator) PrepareGraphDefs() ([]*mkr.GraphDefsParam, error) {
 			"agent.runtime": {
 				Label: "Agent Runtime",
 				Unit:  "integer",
-				Metrics: []customGraphMetricDef{
+				Metrics: []CustomGraphMetricDef{
 					{Name: "goroutine_num", Label: "Goroutine Num"},
 				},
 			},
+			"agent.queue": {
+				Label: "Agent Queue",
+				Unit:  "integer",
+				Metrics: []CustomGraphMetricDef{
+					{Name: "post", Label: "Metric Values to Post"},
+					{Name: "check_report", Label: "Check Reports"},
+				},
+			},
+			"agent.post_latency": {
+				Label: "Agent Post Latency",
+				Unit:  "milliseconds",
+				Metrics: []CustomGraphMetricDef{
+					{Name: "max", Label: "Max"},
+				},
+			},
+			"agent.events": {
+				Label: "Agent Events",
+				Unit:  "integer",
+				Metrics: []CustomGraphMetricDef{
+					{Name: "post_failures", Label: "Post Failures"},
+					{Name: "dropped_ticks", Label: "Dropped Ticks"},
+				},
+			},
+			"agent.plugin.duration": {
+				Label: "Agent Plugin Execution Duration",
+				Unit:  "seconds",
+				Metrics: []CustomGraphMetricDef{
+					{Name: "*", Label: "%1"},
+				},
+			},
+			"agent.plugin.exit_code": {
+				Label: "Agent Plugin Exit Code",
+				Unit:  "integer",
+				Metrics: []CustomGraphMetricDef{
+					{Name: "*", Label: "%1"},
+				},
+			},
+			"agent.plugin.timeouts": {
+				Label: "Agent Plugin Timeouts",
+				Unit:  "integer",
+				Metrics: []CustomGraphMetricDef{
+					{Name: "*", Label: "%1"},
+				},
+			},
 		},
 	}
 	return makeGraphDefsParam(meta), nil
@@ -0,0 +1,249 @@
+package metrics
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/mackerelio/mackerel-agent/cmdutil"
+	"github.com/mackerelio/mackerel-agent/util"
+)
+
+// AgentStats records the states of the running agent itself,
+// which are referred by the self check and AgentGenerator.
+// The methods can be called on a nil *AgentStats, and do nothing.
+type AgentStats struct {
+	mu sync.Mutex
+
+	postFailingSince time.Time
+	postFailures     int
+	postLatencyMax   time.Duration
+	graphDefsErr     error
+	droppedTicks     int
+	plugins          map[string]*pluginStats
+	clockOffset      time.Duration
+	clockObserved    bool
+
+	postQueueLength    func() int
+	checkReportBacklog func() int
+}
+
+type pluginStats struct {
+	failures int // consecutive failures
+	duration time.Duration
+	exitCode int
+	timeouts int
+}
+
+// RecordPost records the result of posting metric values.
+func (s *AgentStats) RecordPost(err error) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err == nil {
+		s.postFailingSince = time.Time{}
+		return
+	}
+	s.postFailures++
+	if s.postFailingSince.IsZero() {
+		s.postFailingSince = time.Now()
+	}
+}
+
+// RecordPostLatency records the duration of a request posting metric values.
+func (s *AgentStats) RecordPostLatency(d time.Duration) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if d > s.postLatencyMax {
+		s.postLatencyMax = d
+	}
+}
+
+// PostFailingSince returns the time when posting metric values started failing.
+// It returns the zero time if the last post succeeded.
+func (s *AgentStats) PostFailingSince() time.Time {
+	if s == nil {
+		return time.Time{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.postFailingSince
+}
+
+// SetPostQueueLengthFunc sets the function which returns the number of
+// metric values waiting for being posted.
+func (s *AgentStats) SetPostQueueLengthFunc(f func() int) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.postQueueLength = f
+}
+
+// PostQueueLength returns the number of metric values waiting for being posted.
+func (s *AgentStats) PostQueueLength() int {
+	if s == nil {
+		return 0
+	}
+	s.mu.Lock()
+	f := s.postQueueLength
+	s.mu.Unlock()
+	if f == nil {
+		return 0
+	}
+	return f()
+}
+
+// SetCheckReportBacklogFunc sets the function which returns the number of
+// check reports waiting for being reported.
+func (s *AgentStats) SetCheckReportBacklogFunc(f func() int) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checkReportBacklog = f
+}
+
+// RecordGraphDefs records the result of creating graph definitions.
+func (s *AgentStats) RecordGraphDefs(err error) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.graphDefsErr = err
+}
+
+// GraphDefsError returns the error of the last graph definitions creation.
+func (s *AgentStats) GraphDefsError() error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.graphDefsErr
+}
+
+// RecordDroppedTick records that collecting metrics is skipped
+// because the previous collections have not finished.
+func (s *AgentStats) RecordDroppedTick() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.droppedTicks++
+}
+
+// RecordClockOffset records the offset of the local clock from the time
+// of the Mackerel API server, which is positive if the local clock is ahead.
+func (s *AgentStats) RecordClockOffset(d time.Duration) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clockOffset = d
+	s.clockObserved = true
+}
+
+// ClockOffset returns the offset recorded last, and whether it has been recorded.
+func (s *AgentStats) ClockOffset() (time.Duration, bool) {
+	if s == nil {
+		return 0, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.clockOffset, s.clockObserved
+}
+
+// RecordPluginExecution records the result of executing the plugin command.
+// The execution is regarded as a failure if err is not nil or exitCode is not zero.
+func (s *AgentStats) RecordPluginExecution(plugin string, d time.Duration, exitCode int, err error) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.plugins == nil {
+		s.plugins = make(map[string]*pluginStats)
+	}
+	p, ok := s.plugins[plugin]
+	if !ok {
+		p = &pluginStats{}
+		s.plugins[plugin] = p
+	}
+	p.duration = d
+	p.exitCode = exitCode
+	if err == nil && exitCode == 0 {
+		p.failures = 0
+	} else {
+		p.failures++
+	}
+	if errors.Is(err, cmdutil.ErrTimedOut) {
+		p.timeouts++
+	}
+}
+
+// PluginFailures returns the numbers of consecutive failures of each failing plugin.
+func (s *AgentStats) PluginFailures() map[string]int {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	failures := make(map[string]int)
+	for name, p := range s.plugins {
+		if p.failures > 0 {
+			failures[name] = p.failures
+		}
+	}
+	return failures
+}
+
+// values returns the diagnostic metric values.
+// The counters are reset to count events until the next call.
+func (s *AgentStats) values() Values {
+	ret, postQueueLength, checkReportBacklog := s.resetCounters()
+	// The functions are called without the lock as they are set from outside.
+	if postQueueLength != nil {
+		ret["custom.agent.queue.post"] = float64(postQueueLength())
+	}
+	if checkReportBacklog != nil {
+		ret["custom.agent.queue.check_report"] = float64(checkReportBacklog())
+	}
+	return ret
+}
+
+// resetCounters returns the values of the counters and resets them, with the
+// functions which return the lengths of the queues.
+func (s *AgentStats) resetCounters() (ret Values, postQueueLength, checkReportBacklog func() int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ret = Values{
+		"custom.agent.events.post_failures": float64(s.postFailures),
+		"custom.agent.events.dropped_ticks": float64(s.droppedTicks),
+	}
+	if s.postLatencyMax > 0 {
+		ret["custom.agent.post_latency.max"] = float64(s.postLatencyMax) / float64(time.Millisecond)
+	}
+	for name, p := range s.plugins {
+		key := util.SanitizeMetricKey(name)
+		ret["custom.agent.plugin.duration."+key] = p.duration.Seconds()
+		ret["custom.agent.plugin.exit_code."+key] = float64(p.exitCode)
+		ret["custom.agent.plugin.timeouts."+key] = float64(p.timeouts)
+		p.timeouts = 0
+	}
+	s.postFailures = 0
+	s.postLatencyMax = 0
+	s.droppedTicks = 0
+	return ret, s.postQueueLength, s.checkReportBacklog
+}
@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mackerelio/mackerel-agent/cmdutil"
+)
+
+func TestAgentStats(t *testing.T) {
+	var s AgentStats
+
+	s.RecordPost(errors.New("network error"))
+	since := s.PostFailingSince()
+	if since.IsZero() {
+		t.Error("PostFailingSince should be set after a failure")
+	}
+	s.RecordPost(errors.New("network error"))
+	if !s.PostFailingSince().Equal(since) {
+		t.Error("PostFailingSince should not change while failing")
+	}
+	s.RecordPost(nil)
+	if !s.PostFailingSince().IsZero() {
+		t.Error("PostFailingSince should be reset after a success")
+	}
+
+	s.RecordGraphDefs(errors.New("bad request"))
+	if s.GraphDefsError() == nil {
+		t.Error("GraphDefsError should be recorded")
+	}
+	s.RecordGraphDefs(nil)
+	if s.GraphDefsError() != nil {
+		t.Error("GraphDefsError should be reset after a success")
+	}
+
+	s.RecordPluginExecution("plugin1", time.Second, 1, nil)
+	s.RecordPluginExecution("plugin1", 30*time.Second, -1, cmdutil.ErrTimedOut)
+	s.RecordPluginExecution("plugin2", time.Second, 1, nil)
+	s.RecordPluginExecution("plugin2", time.Second, 0, nil)
+	if got, want := s.PluginFailures(), map[string]int{"plugin1": 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("PluginFailures should be %v but %v", want, got)
+	}
+}
+
+func TestAgentStats_values(t *testing.T) {
+	var s AgentStats
+	s.RecordPost(errors.New("network error"))
+	s.RecordPost(errors.New("network error"))
+	s.RecordPostLatency(200 * time.Millisecond)
+	s.RecordPostLatency(100 * time.Millisecond)
+	s.RecordDroppedTick()
+	s.SetPostQueueLengthFunc(func() int { return 3 })
+	s.SetCheckReportBacklogFunc(func() int { return 4 })
+	s.RecordPluginExecution("my.plugin", 30*time.Second, -1, cmdutil.ErrTimedOut)
+
+	expected := Values{
+		"custom.agent.events.post_failures":       2,
+		"custom.agent.events.dropped_ticks":       1,
+		"custom.agent.post_latency.max":           200,
+		"custom.agent.queue.post":                 3,
+		"custom.agent.queue.check_report":         4,
+		"custom.agent.plugin.duration.my_plugin":  30,
+		"custom.agent.plugin.exit_code.my_plugin": -1,
+		"custom.agent.plugin.timeouts.my_plugin":  1,
+	}
+	if got := s.values(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("values should be %v but %v", expected, got)
+	}
+
+	// counters are reset
+	expected["custom.agent.events.post_failures"] = 0
+	expected["custom.agent.events.dropped_ticks"] = 0
+	expected["custom.agent.plugin.timeouts.my_plugin"] = 0
+	delete(expected, "custom.agent.post_latency.max")
+	if got := s.values(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("values should be %v but %v", expected, got)
+	}
+}
+
+func TestAgentStats_valuesWithReentrantFunc(t *testing.T) {
+	var s AgentStats
+	// The function may use the stats, which should not deadlock.
+	s.SetPostQueueLengthFunc(func() int {
+		s.RecordDroppedTick()
+		return 1
+	})
+	if got := s.values()["custom.agent.queue.post"]; got != 1 {
+		t.Errorf("custom.agent.queue.post should be 1 but %v", got)
+	}
+}
+
+func TestAgentStats_Nil(t *testing.T) {
+	var s *AgentStats
+	s.RecordPost(errors.New("network error"))
+	s.RecordPostLatency(time.Second)
+	s.RecordGraphDefs(errors.New("bad request"))
+	s.RecordDroppedTick()
+	s.SetPostQueueLengthFunc(func() int { return 1 })
+	s.SetCheckReportBacklogFunc(func() int { return 1 })
+	s.RecordPluginExecution("plugin1", time.Second, 1, nil)
+	if !s.PostFailingSince().IsZero() || s.GraphDefsError() != nil || s.PluginFailures() != nil {
+		t.Error("nil AgentStats should not record anything")
+	}
+}
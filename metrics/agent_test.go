@@ -22,3 +22,27 @@ func TestAgentGenerate(t *testing.T) {
 		}
 	}
 }
+
+func TestAgentGenerateWithStats(t *testing.T) {
+	stats := &AgentStats{}
+	stats.SetPostQueueLengthFunc(func() int { return 2 })
+	g := &AgentGenerator{Stats: stats}
+	values, _ := g.Generate()
+
+	for _, name := range []string{"custom.agent.queue.post", "custom.agent.events.post_failures", "custom.agent.memory.alloc"} {
+		if _, ok := values[name]; !ok {
+			t.Errorf("AgentGenerator should generate metric value for '%s'", name)
+		}
+	}
+
+	defs, _ := g.PrepareGraphDefs()
+	names := map[string]bool{}
+	for _, def := range defs {
+		names[def.Name] = true
+	}
+	for _, name := range []string{"custom.agent.queue", "custom.agent.plugin.duration"} {
+		if !names[name] {
+			t.Errorf("graph definition %s should be prepared", name)
+		}
+	}
+}
@@ -0,0 +1,346 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mackerelio/golib/logging"
+	"github.com/mackerelio/mackerel-agent/util"
+	mkr "github.com/mackerelio/mackerel-client-go"
+)
+
+/*
+collect resource usage of each Docker container via the Docker Engine API
+
+`custom.docker.cpu.{container}.usage`: The CPU time since the previous collection as percentage of a CPU core
+
+`custom.docker.memory.{container}.{usage,limit}`: The memory usage (excluding inactive file caches as `docker stats` does) and the limit in bytes
+
+`custom.docker.network.{container}.{rx,tx}`: The bytes received/transmitted per second on all interfaces
+
+`custom.docker.blkio.{container}.{read,write}`: The bytes read/written per second on all devices
+
+container = the sanitized name of the running container
+
+The Engine API of Podman is also supported, which is compatible with Docker's.
+*/
+
+// DefaultDockerSocket is the default path to the Unix socket of the Docker Engine API
+const DefaultDockerSocket = "/var/run/docker.sock"
+
+var dockerLogger = logging.GetLogger("metrics.docker")
+
+var dockerRequestTimeout = 10 * time.Second
+
+// Generate gives up the stats of the containers not fetched in this timeout,
+// fetching them with at most dockerStatsConcurrency requests at a time.
+var dockerCollectTimeout = 30 * time.Second
+
+const dockerStatsConcurrency = 4
+
+// DockerGenerator generates resource usage of each Docker container
+type DockerGenerator struct {
+	Socket string // defaults to DefaultDockerSocket
+
+	once   sync.Once
+	client *http.Client
+
+	mu         sync.Mutex
+	prevValues map[string]*dockerCounters
+	prevTime   time.Time
+}
+
+// DockerContainer is an item of the response of GET /containers/json
+type DockerContainer struct {
+	ID      string   `json:"Id"`
+	Names   []string `json:"Names"`
+	Image   string   `json:"Image"`
+	State   string   `json:"State"`
+	Created int64    `json:"Created"`
+}
+
+// Name returns the name of the container without the leading slash.
+func (c *DockerContainer) Name() string {
+	if len(c.Names) == 0 {
+		return c.ID
+	}
+	return strings.TrimPrefix(c.Names[0], "/")
+}
+
+// the response of GET /containers/{id}/stats
+type dockerStats struct {
+	CPUStats struct {
+		CPUUsage struct {
+			TotalUsage uint64 `json:"total_usage"` // nanoseconds
+		} `json:"cpu_usage"`
+	} `json:"cpu_stats"`
+	MemoryStats struct {
+		Usage uint64            `json:"usage"`
+		Limit uint64            `json:"limit"`
+		Stats map[string]uint64 `json:"stats"`
+	} `json:"memory_stats"`
+	Networks map[string]struct {
+		RxBytes uint64 `json:"rx_bytes"`
+		TxBytes uint64 `json:"tx_bytes"`
+	} `json:"networks"`
+	BlkioStats struct {
+		IoServiceBytesRecursive []struct {
+			Op    string `json:"op"`
+			Value uint64 `json:"value"`
+		} `json:"io_service_bytes_recursive"`
+	} `json:"blkio_stats"`
+}
+
+type dockerCounters struct {
+	CPU     uint64
+	RxBytes uint64
+	TxBytes uint64
+	Read    uint64
+	Write   uint64
+}
+
+func (g *DockerGenerator) httpClient() *http.Client {
+	g.once.Do(func() {
+		socket := g.Socket
+		if socket == "" {
+			socket = DefaultDockerSocket
+		}
+		g.client = &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, "unix", socket)
+				},
+			},
+		}
+	})
+	return g.client
+}
+
+func (g *DockerGenerator) get(ctx context.Context, path string, v any) error {
+	// The host part is ignored since the client always dials the socket.
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://docker"+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := g.httpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", path, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// Containers returns the running containers.
+func (g *DockerGenerator) Containers() ([]*DockerContainer, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dockerRequestTimeout)
+	defer cancel()
+	var containers []*DockerContainer
+	if err := g.get(ctx, "/containers/json", &containers); err != nil {
+		return nil, err
+	}
+	return containers, nil
+}
+
+// Generate resource usage of each Docker container
+func (g *DockerGenerator) Generate() (Values, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	containers, err := g.Containers()
+	if err != nil {
+		dockerLogger.Errorf("Failed to list containers (skip these metrics): %s", err)
+		return nil, err
+	}
+	ret := make(Values)
+	currValues := make(map[string]*dockerCounters)
+	for i, stats := range g.containerStats(containers) {
+		if stats == nil {
+			continue
+		}
+		name := util.SanitizeMetricKey(containers[i].Name())
+		ret["custom.docker.memory."+name+".usage"] = float64(dockerMemoryUsage(stats))
+		ret["custom.docker.memory."+name+".limit"] = float64(stats.MemoryStats.Limit)
+		currValues[name] = newDockerCounters(stats)
+	}
+
+	now := time.Now()
+	prevValues, prevTime := g.prevValues, g.prevTime
+	g.prevValues, g.prevTime = currValues, now
+	if prevValues != nil {
+		for k, v := range calcDockerRates(currValues, prevValues, now.Sub(prevTime)) {
+			ret[k] = v
+		}
+	}
+	return ret, nil
+}
+
+// containerStats fetches the stats of each container concurrently within
+// dockerCollectTimeout. The stats of the containers failed are nil.
+func (g *DockerGenerator) containerStats(containers []*DockerContainer) []*dockerStats {
+	ctx, cancel := context.WithTimeout(context.Background(), dockerCollectTimeout)
+	defer cancel()
+
+	ret := make([]*dockerStats, len(containers))
+	sem := make(chan struct{}, dockerStatsConcurrency)
+	var wg sync.WaitGroup
+	for i, c := range containers {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, c *DockerContainer) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			var stats dockerStats
+			if err := g.get(ctx, "/containers/"+c.ID+"/stats?stream=false&one-shot=true", &stats); err != nil {
+				dockerLogger.Warningf("Failed to get stats of container %s: %s", c.Name(), err)
+				return
+			}
+			ret[i] = &stats
+		}(i, c)
+	}
+	wg.Wait()
+	return ret
+}
+
+// dockerMemoryUsage subtracts inactive file caches from the usage as `docker stats` does.
+func dockerMemoryUsage(stats *dockerStats) uint64 {
+	usage := stats.MemoryStats.Usage
+	inactive, ok := stats.MemoryStats.Stats["inactive_file"] // cgroup v2
+	if !ok {
+		inactive = stats.MemoryStats.Stats["total_inactive_file"] // cgroup v1
+	}
+	return usage - min(inactive, usage)
+}
+
+func newDockerCounters(stats *dockerStats) *dockerCounters {
+	c := &dockerCounters{CPU: stats.CPUStats.CPUUsage.TotalUsage}
+	for _, n := range stats.Networks {
+		c.RxBytes += n.RxBytes
+		c.TxBytes += n.TxBytes
+	}
+	for _, io := range stats.BlkioStats.IoServiceBytesRecursive {
+		// "Read" on cgroup v1 and "read" on cgroup v2
+		switch strings.ToLower(io.Op) {
+		case "read":
+			c.Read += io.Value
+		case "write":
+			c.Write += io.Value
+		}
+	}
+	return c
+}
+
+func calcDockerRates(currValues, prevValues map[string]*dockerCounters, elapsed time.Duration) Values {
+	ret := make(Values)
+	if elapsed <= 0 {
+		return ret
+	}
+	seconds := elapsed.Seconds()
+	for name, curr := range currValues {
+		prev, ok := prevValues[name]
+		if !ok {
+			continue
+		}
+		ret["custom.docker.cpu."+name+".usage"] = float64(util.DiffResettableCounter(curr.CPU, prev.CPU)) * 100 / float64(elapsed.Nanoseconds())
+		ret["custom.docker.network."+name+".rx"] = float64(util.DiffResettableCounter(curr.RxBytes, prev.RxBytes)) / seconds
+		ret["custom.docker.network."+name+".tx"] = float64(util.DiffResettableCounter(curr.TxBytes, prev.TxBytes)) / seconds
+		ret["custom.docker.blkio."+name+".read"] = float64(util.DiffResettableCounter(curr.Read, prev.Read)) / seconds
+		ret["custom.docker.blkio."+name+".write"] = float64(util.DiffResettableCounter(curr.Write, prev.Write)) / seconds
+	}
+	return ret
+}
+
+// DockerInventory is the container inventory posted as the host metadata
+type DockerInventory struct {
+	Containers []DockerInventoryItem `json:"containers"`
+}
+
+// DockerInventoryItem is a container in DockerInventory
+type DockerInventoryItem struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Image   string `json:"image"`
+	State   string `json:"state"`
+	Created string `json:"created"`
+}
+
+// Inventory returns the container inventory for the host metadata.
+func (g *DockerGenerator) Inventory() (any, error) {
+	containers, err := g.Containers()
+	if err != nil {
+		return nil, err
+	}
+	inventory := DockerInventory{Containers: make([]DockerInventoryItem, 0, len(containers))}
+	for _, c := range containers {
+		id := c.ID
+		if len(id) > 12 {
+			id = id[:12]
+		}
+		inventory.Containers = append(inventory.Containers, DockerInventoryItem{
+			ID:      id,
+			Name:    c.Name(),
+			Image:   c.Image,
+			State:   c.State,
+			Created: time.Unix(c.Created, 0).UTC().Format(time.RFC3339),
+		})
+	}
+	// keep the order stable not to post the unchanged metadata
+	sort.Slice(inventory.Containers, func(i, j int) bool {
+		return inventory.Containers[i].Name < inventory.Containers[j].Name
+	})
+	return inventory, nil
+}
+
+// CustomIdentifier for PluginGenerator interface
+func (g *DockerGenerator) CustomIdentifier() *string {
+	return nil
+}
+
+// PrepareGraphDefs for PluginGenerator interface
+func (g *DockerGenerator) PrepareGraphDefs() ([]*mkr.GraphDefsParam, error) {
+	return NewGraphDefsParam(map[string]CustomGraphDef{
+		"docker.cpu.#": {
+			Label: "Docker CPU %1",
+			Unit:  "percentage",
+			Metrics: []CustomGraphMetricDef{
+				{Name: "usage", Label: "Usage"},
+			},
+		},
+		"docker.memory.#": {
+			Label: "Docker Memory %1",
+			Unit:  "bytes",
+			Metrics: []CustomGraphMetricDef{
+				{Name: "usage", Label: "Usage"},
+				{Name: "limit", Label: "Limit"},
+			},
+		},
+		"docker.network.#": {
+			Label: "Docker Network %1",
+			Unit:  "bytes/sec",
+			Metrics: []CustomGraphMetricDef{
+				{Name: "rx", Label: "Received"},
+				{Name: "tx", Label: "Transmitted"},
+			},
+		},
+		"docker.blkio.#": {
+			Label: "Docker Block I/O %1",
+			Unit:  "bytes/sec",
+			Metrics: []CustomGraphMetricDef{
+				{Name: "read", Label: "Read"},
+				{Name: "write", Label: "Write"},
+			},
+		},
+	}), nil
+}
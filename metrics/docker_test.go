@@ -0,0 +1,175 @@
+//go:build !windows
+// +build !windows
+
+package metrics
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// newFakeDockerServer serves the Docker Engine API on a Unix socket.
+func newFakeDockerServer(t *testing.T, handler http.Handler) string {
+	t.Helper()
+	// The path of a Unix socket must be short.
+	dir, err := os.MkdirTemp("", "docker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	socket := filepath.Join(dir, "docker.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: handler}
+	go srv.Serve(l)
+	t.Cleanup(func() { srv.Close() })
+	return socket
+}
+
+func TestDockerGenerator(t *testing.T) {
+	var cpuUsage uint64 = 1000000000
+	mux := http.NewServeMux()
+	mux.HandleFunc("/containers/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{"Id": "8dfafdbc3a40aaaaaaaa", "Names": ["/web.1"], "Image": "nginx:latest", "State": "running", "Created": 1700000000},
+			{"Id": "3176a2479c92bbbbbbbb", "Names": ["/db"], "Image": "postgres:16", "State": "running", "Created": 1690000000}
+		]`))
+	})
+	mux.HandleFunc("/containers/8dfafdbc3a40aaaaaaaa/stats", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("stream") != "false" {
+			t.Errorf("stats should not be streamed: %s", r.URL)
+		}
+		cpuUsage += 500000000
+		json.NewEncoder(w).Encode(map[string]any{
+			"cpu_stats": map[string]any{"cpu_usage": map[string]any{"total_usage": cpuUsage}},
+			"memory_stats": map[string]any{
+				"usage": 104857600,
+				"limit": 536870912,
+				"stats": map[string]any{"inactive_file": 4857600},
+			},
+			"networks": map[string]any{"eth0": map[string]any{"rx_bytes": 1024, "tx_bytes": 2048}},
+			"blkio_stats": map[string]any{"io_service_bytes_recursive": []map[string]any{
+				{"major": 8, "minor": 0, "op": "read", "value": 4096},
+				{"major": 8, "minor": 0, "op": "write", "value": 8192},
+			}},
+		})
+	})
+	mux.HandleFunc("/containers/3176a2479c92bbbbbbbb/stats", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no such container", http.StatusNotFound)
+	})
+	g := &DockerGenerator{Socket: newFakeDockerServer(t, mux)}
+
+	values, err := g.Generate()
+	if err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+	expect := Values{
+		"custom.docker.memory.web_1.usage": 100000000,
+		"custom.docker.memory.web_1.limit": 536870912,
+	}
+	if !reflect.DeepEqual(values, expect) {
+		t.Errorf("values should be %+v but %+v", expect, values)
+	}
+
+	values, err = g.Generate()
+	if err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+	for _, name := range []string{
+		"custom.docker.cpu.web_1.usage",
+		"custom.docker.network.web_1.rx",
+		"custom.docker.network.web_1.tx",
+		"custom.docker.blkio.web_1.read",
+		"custom.docker.blkio.web_1.write",
+	} {
+		if _, ok := values[name]; !ok {
+			t.Errorf("values should have %s: %+v", name, values)
+		}
+	}
+
+	inventory, err := g.Inventory()
+	if err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+	expectInventory := DockerInventory{Containers: []DockerInventoryItem{
+		{ID: "3176a2479c92", Name: "db", Image: "postgres:16", State: "running", Created: "2023-07-22T04:26:40Z"},
+		{ID: "8dfafdbc3a40", Name: "web.1", Image: "nginx:latest", State: "running", Created: "2023-11-14T22:13:20Z"},
+	}}
+	if !reflect.DeepEqual(inventory, expectInventory) {
+		t.Errorf("inventory should be %+v but %+v", expectInventory, inventory)
+	}
+}
+
+func TestDockerGenerator_CollectTimeout(t *testing.T) {
+	defer func(d time.Duration) { dockerCollectTimeout = d }(dockerCollectTimeout)
+	dockerCollectTimeout = 200 * time.Millisecond
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/containers/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{"Id": "ffff", "Names": ["/web"]}, {"Id": "aaaa", "Names": ["/hung1"]},
+			{"Id": "bbbb", "Names": ["/hung2"]}, {"Id": "cccc", "Names": ["/hung3"]},
+			{"Id": "dddd", "Names": ["/hung4"]}, {"Id": "eeee", "Names": ["/hung5"]}
+		]`))
+	})
+	mux.HandleFunc("/containers/ffff/stats", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"memory_stats": {"usage": 1024, "limit": 4096}}`))
+	})
+	// The stats of the other containers do not respond until the request is canceled.
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	g := &DockerGenerator{Socket: newFakeDockerServer(t, mux)}
+
+	start := time.Now()
+	values, err := g.Generate()
+	if err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("Generate should give up hung containers within the timeout but took %s", d)
+	}
+	expect := Values{
+		"custom.docker.memory.web.usage": 1024,
+		"custom.docker.memory.web.limit": 4096,
+	}
+	if !reflect.DeepEqual(values, expect) {
+		t.Errorf("values should be %+v but %+v", expect, values)
+	}
+}
+
+func TestDockerGenerator_NoDaemon(t *testing.T) {
+	g := &DockerGenerator{Socket: filepath.Join(t.TempDir(), "none.sock")}
+	if _, err := g.Generate(); err == nil {
+		t.Error("should raise error without the daemon")
+	}
+}
+
+func TestCalcDockerRates(t *testing.T) {
+	prev := map[string]*dockerCounters{
+		"web": {CPU: 1000000000, RxBytes: 0, TxBytes: 600, Read: 0, Write: 0},
+	}
+	curr := map[string]*dockerCounters{
+		"web": {CPU: 31000000000, RxBytes: 6000, TxBytes: 1200, Read: 60000, Write: 120},
+		"new": {CPU: 1},
+	}
+	values := calcDockerRates(curr, prev, 60*time.Second)
+	expect := Values{
+		"custom.docker.cpu.web.usage":   50,
+		"custom.docker.network.web.rx":  100,
+		"custom.docker.network.web.tx":  10,
+		"custom.docker.blkio.web.read":  1000,
+		"custom.docker.blkio.web.write": 2,
+	}
+	if !reflect.DeepEqual(values, expect) {
+		t.Errorf("values should be %+v but %+v", expect, values)
+	}
+}
@@ -6,6 +6,7 @@ package metrics
 import (
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/mackerelio/go-osstat/network"
@@ -16,37 +17,42 @@ import (
 /*
 collect network interface I/O
 
-`interface.{interface}.{metric}.delta`: The increased amount of network I/O per minute retrieved from /proc/net/dev
+`interface.{interface}.{metric}.delta`: The increased amount of network I/O per second since the previous collection retrieved from /proc/net/dev
 
 interface = "eth0", "eth1" and so on... ("en0" on darwin)
+
+The generator keeps the values of the previous collection, so it generates no values at the first collection.
 */
 
 // InterfaceGenerator generates interface metric values
 type InterfaceGenerator struct {
 	IgnoreRegexp *regexp.Regexp
-	Interval     time.Duration
+
+	mu         sync.Mutex
+	prevValues map[string]uint64
+	prevTime   time.Time
 }
 
 var interfaceLogger = logging.GetLogger("metrics.interface")
 
 // Generate interface metric values
 func (g *InterfaceGenerator) Generate() (Values, error) {
-	prevValues, err := g.collectInterfacesValues()
-	if err != nil {
-		return nil, err
-	}
-
-	time.Sleep(g.Interval)
+	g.mu.Lock()
+	defer g.mu.Unlock()
 
 	currValues, err := g.collectInterfacesValues()
 	if err != nil {
 		return nil, err
 	}
+	now := time.Now()
+	prevValues, prevTime := g.prevValues, g.prevTime
+	g.prevValues, g.prevTime = currValues, now
 
 	ret := make(map[string]float64)
+	elapsed := now.Sub(prevTime).Seconds()
 	for name, prevValue := range prevValues {
-		if currValue, ok := currValues[name]; ok && currValue >= prevValue {
-			ret[name+".delta"] = float64(currValue-prevValue) / g.Interval.Seconds()
+		if currValue, ok := currValues[name]; ok {
+			ret[name+".delta"] = float64(util.DiffResettableCounter(currValue, prevValue)) / elapsed
 		}
 	}
 
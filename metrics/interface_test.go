@@ -11,11 +11,20 @@ import (
 )
 
 func TestInterfaceGenerator(t *testing.T) {
-	g := &InterfaceGenerator{nil, 1 * time.Second}
+	g := &InterfaceGenerator{}
 	values, err := g.Generate()
 	if err != nil {
 		t.Errorf("error should be nil but got: %s", err)
 	}
+	if len(values) != 0 {
+		t.Errorf("first collection should not generate values: %v", values)
+	}
+
+	time.Sleep(1 * time.Second)
+	values, err = g.Generate()
+	if err != nil {
+		t.Errorf("error should be nil but got: %s", err)
+	}
 
 	metrics := []string{"rxBytes", "txBytes"}
 
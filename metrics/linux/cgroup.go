@@ -0,0 +1,247 @@
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"math"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mackerelio/go-osstat/memory"
+	"github.com/mackerelio/golib/logging"
+	"github.com/mackerelio/mackerel-agent/metrics"
+	"github.com/mackerelio/mackerel-agent/util"
+	mkr "github.com/mackerelio/mackerel-client-go"
+)
+
+/*
+collect CPU and memory usage of the cgroup v2 which the agent belongs to,
+instead of the host, when `[cgroup] view = "cgroup"` is configured
+
+`cpu.{user,system,idle}.percentage`: The CPU time of the cgroup since the previous collection as percentage of the CPU quota x 100, retrieved from cpu.stat and cpu.max
+
+The quota is the smallest one of the cgroup and its ancestors, or the number of CPUs if unlimited.
+
+`memory.{total,used,mem_available}`: The memory size of the cgroup, retrieved from memory.max, memory.current and memory.stat
+
+Metrics "total" is the smallest memory.max of the cgroup and its ancestors, or MemTotal in /proc/meminfo if unlimited.
+Metrics "used" is calculated by (memory.current - inactive_file) as `docker stats` does.
+
+`memory.{swap_total,swap_free}`: The swap size of the cgroup, retrieved from memory.swap.max and memory.swap.current (only if limited)
+
+`custom.cgroup.cpu_throttling.{periods,throttled}`: The enforcement periods elapsed/throttled per second, retrieved from cpu.stat
+
+`custom.cgroup.cpu_throttled_time.seconds`: The total time throttled per second
+
+cat cpu.stat sample:
+	usage_usec 1204611
+	user_usec 907353
+	system_usec 297258
+	nr_periods 325
+	nr_throttled 18
+	throttled_usec 902271
+
+cat cpu.max sample:
+	200000 100000
+*/
+
+var cgroupLogger = logging.GetLogger("metrics.cgroup")
+
+// CgroupCPUGenerator generates CPU metric values of the cgroup
+type CgroupCPUGenerator struct {
+	Cgroup *util.Cgroup
+
+	mu         sync.Mutex
+	prevValues map[string]uint64
+	prevTime   time.Time
+}
+
+// Generate CPU metric values of the cgroup
+func (g *CgroupCPUGenerator) Generate() (metrics.Values, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	currValues, err := g.Cgroup.ReadKeyedValues("cpu.stat")
+	if err != nil {
+		cgroupLogger.Errorf("Failed to read cpu.stat (skip these metrics): %s", err)
+		return nil, err
+	}
+	quota, err := g.Cgroup.CPUQuota(runtime.NumCPU())
+	if err != nil {
+		cgroupLogger.Errorf("Failed to read cpu.max (skip these metrics): %s", err)
+		return nil, err
+	}
+	now := time.Now()
+	prevValues, prevTime := g.prevValues, g.prevTime
+	g.prevValues, g.prevTime = currValues, now
+	if prevValues == nil {
+		return metrics.Values{}, nil
+	}
+	return calcCgroupCPUUsage(currValues, prevValues, now.Sub(prevTime), quota), nil
+}
+
+func calcCgroupCPUUsage(currValues, prevValues map[string]uint64, elapsed time.Duration, quota float64) metrics.Values {
+	elapsedUsec := float64(elapsed.Microseconds())
+	if elapsedUsec <= 0 {
+		return metrics.Values{}
+	}
+	percentage := func(name string) float64 {
+		return float64(util.DiffResettableCounter(currValues[name], prevValues[name])) * 100 / elapsedUsec
+	}
+	user := percentage("user_usec")
+	system := percentage("system_usec")
+	return metrics.Values{
+		"cpu.user.percentage":   user,
+		"cpu.system.percentage": system,
+		"cpu.idle.percentage":   math.Max(quota*100-user-system, 0),
+	}
+}
+
+// CgroupMemoryGenerator generates memory metric values of the cgroup
+type CgroupMemoryGenerator struct {
+	Cgroup *util.Cgroup
+}
+
+// Generate memory metric values of the cgroup
+func (g *CgroupMemoryGenerator) Generate() (metrics.Values, error) {
+	current, err := g.readUint("memory.current")
+	if err != nil {
+		cgroupLogger.Errorf("Failed to read memory.current (skip these metrics): %s", err)
+		return nil, err
+	}
+	stat, err := g.Cgroup.ReadKeyedValues("memory.stat")
+	if err != nil {
+		cgroupLogger.Errorf("Failed to read memory.stat (skip these metrics): %s", err)
+		return nil, err
+	}
+	total, limited, err := g.Cgroup.MemoryLimit("memory.max")
+	if err != nil {
+		cgroupLogger.Errorf("Failed to read memory.max (skip these metrics): %s", err)
+		return nil, err
+	}
+	if !limited {
+		mem, err := memory.Get()
+		if err != nil {
+			cgroupLogger.Errorf("Failed to get memory statistics (skip these metrics): %s", err)
+			return nil, err
+		}
+		total = mem.Total
+	}
+
+	ret := calcCgroupMemory(total, current, stat)
+
+	// memory.swap.* do not exist without swap accounting.
+	swapTotal, limited, err := g.Cgroup.MemoryLimit("memory.swap.max")
+	if err != nil {
+		cgroupLogger.Warningf("Failed to read memory.swap.max: %s", err)
+		return ret, nil
+	}
+	if limited {
+		swapCurrent, err := g.readUint("memory.swap.current")
+		if err != nil {
+			cgroupLogger.Warningf("Failed to read memory.swap.current: %s", err)
+			return ret, nil
+		}
+		ret["memory.swap_total"] = float64(swapTotal)
+		ret["memory.swap_free"] = float64(swapTotal - min(swapCurrent, swapTotal))
+	}
+	return ret, nil
+}
+
+func (g *CgroupMemoryGenerator) readUint(name string) (uint64, error) {
+	out, err := g.Cgroup.ReadFile(name)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(out)), 10, 64)
+}
+
+func calcCgroupMemory(total, current uint64, stat map[string]uint64) metrics.Values {
+	used := current - min(stat["inactive_file"], current)
+	return metrics.Values{
+		"memory.total":         float64(total),
+		"memory.used":          float64(used),
+		"memory.mem_available": float64(total - min(used, total)),
+	}
+}
+
+// CgroupThrottlingGenerator generates CPU throttling metric values of the cgroup
+type CgroupThrottlingGenerator struct {
+	Cgroup *util.Cgroup
+
+	mu         sync.Mutex
+	prevValues map[string]uint64
+	prevTime   time.Time
+}
+
+// Generate CPU throttling metric values of the cgroup
+func (g *CgroupThrottlingGenerator) Generate() (metrics.Values, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	currValues, err := g.Cgroup.ReadKeyedValues("cpu.stat")
+	if err != nil {
+		cgroupLogger.Errorf("Failed to read cpu.stat (skip these metrics): %s", err)
+		return nil, err
+	}
+	now := time.Now()
+	prevValues, prevTime := g.prevValues, g.prevTime
+	g.prevValues, g.prevTime = currValues, now
+	if prevValues == nil {
+		return metrics.Values{}, nil
+	}
+	return calcCgroupThrottling(currValues, prevValues, now.Sub(prevTime)), nil
+}
+
+func calcCgroupThrottling(currValues, prevValues map[string]uint64, elapsed time.Duration) metrics.Values {
+	ret := make(metrics.Values)
+	if elapsed <= 0 {
+		return ret
+	}
+	keys := map[string]string{
+		"nr_periods":   "custom.cgroup.cpu_throttling.periods",
+		"nr_throttled": "custom.cgroup.cpu_throttling.throttled",
+	}
+	// nr_* and throttled_usec exist only if the cpu controller is enabled.
+	for name, key := range keys {
+		curr, ok := currValues[name]
+		if !ok {
+			continue
+		}
+		ret[key] = float64(util.DiffResettableCounter(curr, prevValues[name])) / elapsed.Seconds()
+	}
+	if curr, ok := currValues["throttled_usec"]; ok {
+		ret["custom.cgroup.cpu_throttled_time.seconds"] = float64(util.DiffResettableCounter(curr, prevValues["throttled_usec"])) / float64(elapsed.Microseconds())
+	}
+	return ret
+}
+
+// CustomIdentifier for PluginGenerator interface
+func (g *CgroupThrottlingGenerator) CustomIdentifier() *string {
+	return nil
+}
+
+// PrepareGraphDefs for PluginGenerator interface
+func (g *CgroupThrottlingGenerator) PrepareGraphDefs() ([]*mkr.GraphDefsParam, error) {
+	return metrics.NewGraphDefsParam(map[string]metrics.CustomGraphDef{
+		"cgroup.cpu_throttling": {
+			Label: "Cgroup CPU Throttling",
+			Unit:  "float",
+			Metrics: []metrics.CustomGraphMetricDef{
+				{Name: "periods", Label: "Periods"},
+				{Name: "throttled", Label: "Throttled"},
+			},
+		},
+		"cgroup.cpu_throttled_time": {
+			Label: "Cgroup CPU Throttled Time",
+			Unit:  "float",
+			Metrics: []metrics.CustomGraphMetricDef{
+				{Name: "seconds", Label: "Seconds"},
+			},
+		},
+	}), nil
+}
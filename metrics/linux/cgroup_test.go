@@ -0,0 +1,86 @@
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mackerelio/mackerel-agent/metrics"
+	"github.com/mackerelio/mackerel-agent/util"
+)
+
+func TestCalcCgroupCPUUsage(t *testing.T) {
+	prev := map[string]uint64{"usage_usec": 1000000, "user_usec": 700000, "system_usec": 300000}
+	curr := map[string]uint64{"usage_usec": 61000000, "user_usec": 42700000, "system_usec": 18300000}
+
+	values := calcCgroupCPUUsage(curr, prev, 60*time.Second, 2)
+	expect := metrics.Values{
+		"cpu.user.percentage":   70,
+		"cpu.system.percentage": 30,
+		"cpu.idle.percentage":   100,
+	}
+	if !reflect.DeepEqual(values, expect) {
+		t.Errorf("values should be %+v but %+v", expect, values)
+	}
+}
+
+func TestCgroupMemoryGenerator(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"memory.max":              "1073741824\n",
+		"ctr/memory.max":          "max\n",
+		"ctr/memory.current":      "314572800\n",
+		"ctr/memory.stat":         "anon 209715200\nfile 104857600\ninactive_file 52428800\n",
+		"ctr/memory.swap.max":     "104857600\n",
+		"ctr/memory.swap.current": "4857600\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	g := &CgroupMemoryGenerator{Cgroup: &util.Cgroup{Root: root, Path: "/ctr"}}
+	values, err := g.Generate()
+	if err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+	expect := metrics.Values{
+		"memory.total":         1073741824,
+		"memory.used":          262144000,
+		"memory.mem_available": 811597824,
+		"memory.swap_total":    104857600,
+		"memory.swap_free":     100000000,
+	}
+	if !reflect.DeepEqual(values, expect) {
+		t.Errorf("values should be %+v but %+v", expect, values)
+	}
+}
+
+func TestCalcCgroupThrottling(t *testing.T) {
+	prev := map[string]uint64{"nr_periods": 100, "nr_throttled": 10, "throttled_usec": 1000000}
+	curr := map[string]uint64{"nr_periods": 700, "nr_throttled": 130, "throttled_usec": 7000000}
+
+	values := calcCgroupThrottling(curr, prev, 60*time.Second)
+	expect := metrics.Values{
+		"custom.cgroup.cpu_throttling.periods":     10,
+		"custom.cgroup.cpu_throttling.throttled":   2,
+		"custom.cgroup.cpu_throttled_time.seconds": 0.1,
+	}
+	if !reflect.DeepEqual(values, expect) {
+		t.Errorf("values should be %+v but %+v", expect, values)
+	}
+
+	// the cpu controller is not enabled
+	values = calcCgroupThrottling(map[string]uint64{"usage_usec": 1}, map[string]uint64{"usage_usec": 0}, 60*time.Second)
+	if len(values) != 0 {
+		t.Errorf("values should be empty: %+v", values)
+	}
+}
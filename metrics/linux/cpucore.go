@@ -0,0 +1,169 @@
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/mackerelio/golib/logging"
+	"github.com/mackerelio/mackerel-agent/metrics"
+	"github.com/mackerelio/mackerel-agent/util"
+	mkr "github.com/mackerelio/mackerel-client-go"
+)
+
+/*
+collect CPU usage of each core
+
+`custom.cpu.core{N}.{metric}.percentage`: The increased amount of CPU time of the core since the previous collection as percentage
+
+metric = "user", "nice", "system", "idle", "iowait", "irq", "softirq", "steal", "guest"
+
+`custom.cpu_max_core.busy`: The highest busy (neither idle nor iowait) percentage among the cores
+
+graph: `custom.cpu.#.#.percentage` for each core and metric, `custom.cpu_max_core.busy`
+
+Since a custom graph can only contain metrics which differ in the last element,
+the metrics of a core cannot be stacked in a graph as the system cpu graph.
+
+cat /proc/stat sample:
+	cpu  2255 34 2290 22625563 6290 127 456 0 0 0
+	cpu0 1132 34 1441 11311718 3675 127 438 0 0 0
+	cpu1 1123 0 849 11313845 2614 0 18 0 0 0
+*/
+
+// CPUCoreGenerator generates CPU usage values of each core
+type CPUCoreGenerator struct {
+	mu       sync.Mutex
+	previous map[string][]uint64
+}
+
+var cpuCoreLogger = logging.GetLogger("metrics.cpuCore")
+
+// the order of columns in /proc/stat
+var cpuCoreMetricNames = []string{
+	"user", "nice", "system", "idle", "iowait", "irq", "softirq", "steal", "guest",
+}
+
+// indexes of cpuCoreMetricNames
+const (
+	cpuCoreUser   = 0
+	cpuCoreIdle   = 3
+	cpuCoreIowait = 4
+	cpuCoreSteal  = 7
+	cpuCoreGuest  = 8
+)
+
+// Generate CPU usage values of each core
+func (g *CPUCoreGenerator) Generate() (metrics.Values, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	out, err := os.ReadFile("/proc/stat")
+	if err != nil {
+		cpuCoreLogger.Errorf("Failed (skip these metrics): %s", err)
+		return nil, err
+	}
+	current := parseProcStatCores(out)
+	previous := g.previous
+	g.previous = current
+	return calcCPUCoreUsage(current, previous), nil
+}
+
+// parseProcStatCores returns the columns of each cpu{N} line in /proc/stat.
+func parseProcStatCores(out []byte) map[string][]uint64 {
+	cores := make(map[string][]uint64)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		cols := strings.Fields(scanner.Text())
+		if len(cols) < 5 || !strings.HasPrefix(cols[0], "cpu") || cols[0] == "cpu" {
+			continue
+		}
+		values := make([]uint64, len(cpuCoreMetricNames))
+		for i := range values {
+			if i+1 >= len(cols) {
+				break
+			}
+			v, err := strconv.ParseUint(cols[i+1], 10, 64)
+			if err != nil {
+				cpuCoreLogger.Warningf("Failed to parse cpu metrics: %s", err)
+				break
+			}
+			values[i] = v
+		}
+		cores["core"+strings.TrimPrefix(cols[0], "cpu")] = values
+	}
+	return cores
+}
+
+func calcCPUCoreUsage(current, previous map[string][]uint64) metrics.Values {
+	ret := make(metrics.Values)
+	if previous == nil {
+		return ret
+	}
+	maxBusy := -1.0
+	for core, curr := range current {
+		prev, ok := previous[core]
+		if !ok {
+			continue
+		}
+		diffs := make([]float64, len(cpuCoreMetricNames))
+		var total float64
+		for i := range cpuCoreMetricNames {
+			c, p := curr[i], prev[i]
+			// Since user includes guest, we subtract guest from user as CPUUsageGenerator does.
+			if i == cpuCoreUser {
+				c, p = c-curr[cpuCoreGuest], p-prev[cpuCoreGuest]
+			}
+			diffs[i] = float64(util.DiffResettableCounter(c, p))
+			if i <= cpuCoreSteal {
+				total += diffs[i]
+			}
+		}
+		total += diffs[cpuCoreGuest]
+		if total == 0 {
+			continue
+		}
+		for i, name := range cpuCoreMetricNames {
+			ret["custom.cpu."+core+"."+name+".percentage"] = diffs[i] * 100.0 / total
+		}
+		busy := (total - diffs[cpuCoreIdle] - diffs[cpuCoreIowait]) * 100.0 / total
+		if busy > maxBusy {
+			maxBusy = busy
+		}
+	}
+	if maxBusy >= 0 {
+		ret["custom.cpu_max_core.busy"] = maxBusy
+	}
+	return ret
+}
+
+// CustomIdentifier for PluginGenerator interface
+func (g *CPUCoreGenerator) CustomIdentifier() *string {
+	return nil
+}
+
+// PrepareGraphDefs for PluginGenerator interface
+func (g *CPUCoreGenerator) PrepareGraphDefs() ([]*mkr.GraphDefsParam, error) {
+	return metrics.NewGraphDefsParam(map[string]metrics.CustomGraphDef{
+		"cpu.#.#": {
+			Label: "CPU %1 %2",
+			Unit:  "percentage",
+			Metrics: []metrics.CustomGraphMetricDef{
+				{Name: "percentage", Label: "%2"},
+			},
+		},
+		"cpu_max_core": {
+			Label: "CPU Max Core Busy",
+			Unit:  "percentage",
+			Metrics: []metrics.CustomGraphMetricDef{
+				{Name: "busy", Label: "Busy"},
+			},
+		},
+	}), nil
+}
@@ -0,0 +1,88 @@
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCPUCoreGenerator(t *testing.T) {
+	g := &CPUCoreGenerator{}
+	values, err := g.Generate()
+	if err != nil {
+		t.Errorf("should not raise error: %v", err)
+	}
+	if len(values) != 0 {
+		t.Errorf("first collection should not generate values: %v", values)
+	}
+
+	time.Sleep(1 * time.Second)
+	values, err = g.Generate()
+	if err != nil {
+		t.Errorf("should not raise error: %v", err)
+	}
+	for _, name := range []string{"custom.cpu.core0.user.percentage", "custom.cpu.core0.idle.percentage", "custom.cpu_max_core.busy"} {
+		if _, ok := values[name]; !ok {
+			t.Errorf("cpu core values should have '%s': %v", name, values)
+		}
+	}
+}
+
+func TestCalcCPUCoreUsage(t *testing.T) {
+	previous := parseProcStatCores([]byte(`cpu  2000 0 1000 7000 0 0 0 0 0 0
+cpu0 1000 0 500 3500 0 0 0 0 0 0
+cpu1 1000 0 500 3500 0 0 0 0 0 0
+intr 114930548 113199788 3 0 5 263 0 4 [... lots more numbers ...]`))
+	current := parseProcStatCores([]byte(`cpu  2950 0 1000 7050 0 0 0 0 0 0
+cpu0 1800 0 500 3600 100 0 0 0 100 0
+cpu1 1050 0 500 3950 0 0 0 0 0 0
+intr 114930548 113199788 3 0 5 263 0 4 [... lots more numbers ...]`))
+
+	values := calcCPUCoreUsage(current, previous)
+	expect := map[string]float64{
+		"custom.cpu.core0.user.percentage":    70,
+		"custom.cpu.core0.nice.percentage":    0,
+		"custom.cpu.core0.system.percentage":  0,
+		"custom.cpu.core0.idle.percentage":    10,
+		"custom.cpu.core0.iowait.percentage":  10,
+		"custom.cpu.core0.irq.percentage":     0,
+		"custom.cpu.core0.softirq.percentage": 0,
+		"custom.cpu.core0.steal.percentage":   0,
+		"custom.cpu.core0.guest.percentage":   10,
+		"custom.cpu.core1.user.percentage":    10,
+		"custom.cpu.core1.nice.percentage":    0,
+		"custom.cpu.core1.system.percentage":  0,
+		"custom.cpu.core1.idle.percentage":    90,
+		"custom.cpu.core1.iowait.percentage":  0,
+		"custom.cpu.core1.irq.percentage":     0,
+		"custom.cpu.core1.softirq.percentage": 0,
+		"custom.cpu.core1.steal.percentage":   0,
+		"custom.cpu.core1.guest.percentage":   0,
+		"custom.cpu_max_core.busy":            80,
+	}
+	if !reflect.DeepEqual(map[string]float64(values), expect) {
+		t.Errorf("values should be %v but %v", expect, values)
+	}
+}
+
+func TestCPUCoreGenerator_PrepareGraphDefs(t *testing.T) {
+	g := &CPUCoreGenerator{}
+	graphs, err := g.PrepareGraphDefs()
+	if err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+	names := make(map[string]bool)
+	for _, graph := range graphs {
+		for _, m := range graph.Metrics {
+			names[m.Name] = true
+		}
+	}
+	for _, name := range []string{"custom.cpu.#.#.percentage", "custom.cpu_max_core.busy"} {
+		if !names[name] {
+			t.Errorf("graph definitions should have %s: %v", name, names)
+		}
+	}
+}
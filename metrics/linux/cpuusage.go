@@ -4,7 +4,7 @@
 package linux
 
 import (
-	"time"
+	"sync"
 
 	"github.com/mackerelio/go-osstat/cpu"
 	"github.com/mackerelio/golib/logging"
@@ -15,66 +15,79 @@ import (
 /*
 collect CPU usage
 
-`cpu.{metric}.percentage`: The increased amount of CPU time per minute as percentage of total CPU cores x 100
+`cpu.{metric}.percentage`: The increased amount of CPU time since the previous collection as percentage of total CPU cores x 100
 
 metric = "user", "nice", "system", "idle", "iowait", "irq", "softirq", "steal", "guest"
 
 graph: stacks `cpu.{metric}.percentage`
+
+The generator keeps the statistics of the previous collection, so it generates no values at the first collection.
 */
 
 // CPUUsageGenerator generates CPU metric values
 type CPUUsageGenerator struct {
-	Interval time.Duration
+	mu       sync.Mutex
+	previous *cpu.Stats
 }
 
 var cpuUsageLogger = logging.GetLogger("metrics.cpuUsage")
 
 // Generate CPU metric values
 func (g *CPUUsageGenerator) Generate() (metrics.Values, error) {
-	previous, err := g.collectProcStatValues()
-	if err != nil {
-		return nil, err
-	}
-
-	time.Sleep(g.Interval)
+	g.mu.Lock()
+	defer g.mu.Unlock()
 
 	current, err := g.collectProcStatValues()
 	if err != nil {
 		return nil, err
 	}
+	previous := g.previous
+	g.previous = current
+	if previous == nil {
+		return metrics.Values{}, nil
+	}
+	return calcCPUUsage(current, previous), nil
+}
 
-	totalDiff := float64(current.Total - previous.Total)
+func calcCPUUsage(current, previous *cpu.Stats) metrics.Values {
+	totalDiff := float64(util.DiffResettableCounter(current.Total, previous.Total))
+	if totalDiff == 0 {
+		return metrics.Values{}
+	}
 	cpuCount := float64(current.CPUCount)
+	diff := func(current, previous uint64) float64 {
+		return float64(util.DiffResettableCounter(current, previous)) * cpuCount * 100.0 / totalDiff
+	}
 
 	// Since cpustat[CPUTIME_USER] includes cpustat[CPUTIME_GUEST], we subtract guest from user for the stacked graph of Mackerel.
 	// https://github.com/torvalds/linux/blob/4ec9f7a18/kernel/sched/cputime.c#L151-L158
 	// We should also subtract guest_nice from nice, but guest_nice is not supported in Mackerel yet.
 	ret := map[string]float64{
-		"cpu.user.percentage":   float64((current.User-current.Guest)-(previous.User-previous.Guest)) * cpuCount * 100.0 / totalDiff,
-		"cpu.nice.percentage":   float64(current.Nice-previous.Nice) * cpuCount * 100.0 / totalDiff,
-		"cpu.system.percentage": float64(current.System-previous.System) * cpuCount * 100.0 / totalDiff,
-		"cpu.idle.percentage":   float64(current.Idle-previous.Idle) * cpuCount * 100.0 / totalDiff,
+		"cpu.user.percentage":   diff(current.User-current.Guest, previous.User-previous.Guest),
+		"cpu.nice.percentage":   diff(current.Nice, previous.Nice),
+		"cpu.system.percentage": diff(current.System, previous.System),
+		"cpu.idle.percentage":   diff(current.Idle, previous.Idle),
 	}
 	if current.StatCount >= 5 {
-		ret["cpu.iowait.percentage"] = float64(util.DiffResettableCounter(current.Iowait, previous.Iowait)) * cpuCount * 100.0 / totalDiff
+		ret["cpu.iowait.percentage"] = diff(current.Iowait, previous.Iowait)
 	}
 	if current.StatCount >= 6 {
-		ret["cpu.irq.percentage"] = float64(current.Irq-previous.Irq) * cpuCount * 100.0 / totalDiff
+		ret["cpu.irq.percentage"] = diff(current.Irq, previous.Irq)
 	}
 	if current.StatCount >= 7 {
-		ret["cpu.softirq.percentage"] = float64(current.Softirq-previous.Softirq) * cpuCount * 100.0 / totalDiff
+		ret["cpu.softirq.percentage"] = diff(current.Softirq, previous.Softirq)
 	}
 	if current.StatCount >= 8 {
-		ret["cpu.steal.percentage"] = float64(current.Steal-previous.Steal) * cpuCount * 100.0 / totalDiff
+		ret["cpu.steal.percentage"] = diff(current.Steal, previous.Steal)
 	}
 	if current.StatCount >= 9 {
-		ret["cpu.guest.percentage"] = float64(current.Guest-previous.Guest) * cpuCount * 100.0 / totalDiff
+		ret["cpu.guest.percentage"] = diff(current.Guest, previous.Guest)
 	}
 	// guest_nice is not yet supported in Mackerel
 	// if current.StatCount >= 10 {
 	// 	ret["cpu.guest_nice.percentage"]=   float64(current.GuestNice - previous.GuestNice) * cpuCount * 100.0 / totalDiff
 	// }
-	return metrics.Values(ret), nil
+	return metrics.Values(ret)
 }
 
 // returns values corresponding to cpuUsageMetricNames, those total and the number of CPUs
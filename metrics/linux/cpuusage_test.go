@@ -7,11 +7,19 @@ import (
 	"math"
 	"testing"
 	"time"
+
+	"github.com/mackerelio/go-osstat/cpu"
 )
 
 func TestCPUUsageGenerate(t *testing.T) {
-	g := &CPUUsageGenerator{1 * time.Second}
+	g := &CPUUsageGenerator{}
 	values, _ := g.Generate()
+	if len(values) != 0 {
+		t.Errorf("first collection should not generate values: %v", values)
+	}
+
+	time.Sleep(1 * time.Second)
+	values, _ = g.Generate()
 
 	var metricNames = []string{
 		"user", "nice", "system", "idle", "iowait",
@@ -38,3 +46,16 @@ func TestCPUUsageGenerate(t *testing.T) {
 
 	t.Logf("cpu metric metrics: %+v", values)
 }
+
+func TestCalcCPUUsage_CounterReset(t *testing.T) {
+	previous := &cpu.Stats{User: 1000, Idle: 3000, Total: 4000, CPUCount: 1, StatCount: 4}
+	current := &cpu.Stats{User: 100, Idle: 300, Total: 400, CPUCount: 1, StatCount: 4}
+	values := calcCPUUsage(current, previous)
+
+	if v := values["cpu.user.percentage"]; v != 25 {
+		t.Errorf("cpu.user.percentage should be 25 but got %f", v)
+	}
+	if v := values["cpu.idle.percentage"]; v != 75 {
+		t.Errorf("cpu.idle.percentage should be 75 but got %f", v)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/mackerelio/golib/logging"
@@ -21,7 +22,7 @@ import (
 /*
 collect disk I/O
 
-`disk.{device}.{metric}.delta`: The increased amount of disk I/O per minute retrieved from /proc/diskstats
+`disk.{device}.{metric}.delta`: The increased amount of disk I/O per second since the previous collection retrieved from /proc/diskstats
 
 device = "sda1", "xvda1" and so on...
 
@@ -39,13 +40,19 @@ cat /proc/diskstats sample:
 	253       2 dm-2 2022 0 42250 488 30822403 0 3942809696 1364721232 0 93348 1382989868
 
 Note that there are more columns in Linux 4.18+, see https://github.com/torvalds/linux/blob/v4.19/Documentation/ABI/testing/procfs-diskstats.
+They are parsed as "discards", "discardsMerged", "sectorsDiscarded", "discardTime", "flushes" and "flushTime" if exist.
+
+The generator keeps the values of the previous collection, so it generates no values at the first collection.
 */
 
 // DiskGenerator XXX
 type DiskGenerator struct {
 	IgnoreRegexp  *regexp.Regexp
-	Interval      time.Duration
 	UseMountpoint bool
+
+	mu         sync.Mutex
+	prevValues metrics.Values
+	prevTime   time.Time
 }
 
 var diskMetricsNames = []string{
@@ -54,6 +61,14 @@ var diskMetricsNames = []string{
 	"ioInProgress", "ioTime", "ioTimeWeighted",
 }
 
+// columns added in Linux 4.18+ (discard) and 5.5+ (flush)
+var diskOptionalMetricsNames = []string{
+	"discards", "discardsMerged", "sectorsDiscarded", "discardTime",
+	"flushes", "flushTime",
+}
+
+var allDiskMetricsNames = append(diskMetricsNames[:len(diskMetricsNames):len(diskMetricsNames)], diskOptionalMetricsNames...)
+
 // metrics for posting to Mackerel
 var postDiskMetricsRegexp = regexp.MustCompile(`^disk\..+\.(reads|writes)$`)
 
@@ -61,26 +76,29 @@ var diskLogger = logging.GetLogger("metrics.disk")
 
 // Generate XXX
 func (g *DiskGenerator) Generate() (metrics.Values, error) {
-	prevValues, err := g.collectDiskstatValues()
-	if err != nil {
-		return nil, err
-	}
-
-	time.Sleep(g.Interval)
+	g.mu.Lock()
+	defer g.mu.Unlock()
 
 	currValues, err := g.collectDiskstatValues()
 	if err != nil {
 		return nil, err
 	}
+	now := time.Now()
+	prevValues, prevTime := g.prevValues, g.prevTime
+	g.prevValues, g.prevTime = currValues, now
 
 	ret := make(map[string]float64)
+	if prevValues == nil {
+		return metrics.Values(ret), nil
+	}
+	elapsed := now.Sub(prevTime).Seconds()
 	for name, value := range prevValues {
 		if !postDiskMetricsRegexp.MatchString(name) {
 			continue
 		}
 		currValue, ok := currValues[name]
 		if ok {
-			ret[name+".delta"] = (currValue - value) / g.Interval.Seconds()
+			ret[name+".delta"] = float64(util.DiffResettableCounter(uint64(currValue), uint64(value))) / elapsed
 		}
 	}
 
@@ -137,10 +155,12 @@ func (g *DiskGenerator) parseDiskStats(out []byte, mapping map[string]string) (m
 			deviceLabel = util.SanitizeMetricKey(mountpoint)
 		}
 
+		names := allDiskMetricsNames[:min(len(values), len(allDiskMetricsNames))]
+
 		deviceResult := make(map[string]float64)
 		hasNonZeroValue := false
-		for i := range diskMetricsNames {
-			key := fmt.Sprintf("disk.%s.%s", deviceLabel, diskMetricsNames[i])
+		for i := range names {
+			key := fmt.Sprintf("disk.%s.%s", deviceLabel, names[i])
 			value, err := strconv.ParseFloat(values[i], 64)
 			if err != nil {
 				diskLogger.Warningf("Failed to parse disk metrics: %s", err)
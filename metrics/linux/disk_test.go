@@ -12,11 +12,20 @@ import (
 )
 
 func TestDiskGenerator(t *testing.T) {
-	g := &DiskGenerator{Interval: 1 * time.Second}
+	g := &DiskGenerator{}
 	values, err := g.Generate()
 	if err != nil {
 		t.Errorf("should not raise error: %v", err)
 	}
+	if len(values) != 0 {
+		t.Errorf("first collection should not generate values: %v", values)
+	}
+
+	time.Sleep(1 * time.Second)
+	values, err = g.Generate()
+	if err != nil {
+		t.Errorf("should not raise error: %v", err)
+	}
 
 	metrics := []string{
 		"reads", "writes",
@@ -36,7 +45,7 @@ func TestDiskGenerator(t *testing.T) {
 }
 
 func TestParseDiskStats(t *testing.T) {
-	g := &DiskGenerator{Interval: 1 * time.Second}
+	g := &DiskGenerator{}
 	// insert empty line intentionally
 	out := []byte(`202       1 xvda1 750193 3037 28116978 368712 16600606 7233846 424712632 23987908 0 2355636 24345740
 
@@ -115,7 +124,7 @@ func TestParseDiskStats(t *testing.T) {
 }
 
 func TestParseDiskStats_MoreFields(t *testing.T) {
-	g := &DiskGenerator{Interval: 1 * time.Second}
+	g := &DiskGenerator{}
 	// There are 18 columns since Linux 4.18+.
 	out := []byte(`202       1 xvda1 750193 3037 28116978 368712 16600606 7233846 424712632 23987908 0 2355636 24345740 0 0 0 0
   7       0 loop0 15 0 0 0 0 0 0 0 0 0 0 0 0 0 0`)
@@ -127,28 +136,36 @@ func TestParseDiskStats_MoreFields(t *testing.T) {
 	}
 
 	expect := metrics.Values{
-		"disk.xvda1.reads":          750193,
-		"disk.xvda1.readsMerged":    3037,
-		"disk.xvda1.sectorsRead":    28116978,
-		"disk.xvda1.readTime":       368712,
-		"disk.xvda1.writes":         16600606,
-		"disk.xvda1.writesMerged":   7233846,
-		"disk.xvda1.sectorsWritten": 424712632,
-		"disk.xvda1.writeTime":      23987908,
-		"disk.xvda1.ioInProgress":   0,
-		"disk.xvda1.ioTime":         2355636,
-		"disk.xvda1.ioTimeWeighted": 24345740,
-		"disk.loop0.reads":          15,
-		"disk.loop0.readsMerged":    0,
-		"disk.loop0.sectorsRead":    0,
-		"disk.loop0.readTime":       0,
-		"disk.loop0.writes":         0,
-		"disk.loop0.writesMerged":   0,
-		"disk.loop0.sectorsWritten": 0,
-		"disk.loop0.writeTime":      0,
-		"disk.loop0.ioInProgress":   0,
-		"disk.loop0.ioTime":         0,
-		"disk.loop0.ioTimeWeighted": 0,
+		"disk.xvda1.reads":            750193,
+		"disk.xvda1.readsMerged":      3037,
+		"disk.xvda1.sectorsRead":      28116978,
+		"disk.xvda1.readTime":         368712,
+		"disk.xvda1.writes":           16600606,
+		"disk.xvda1.writesMerged":     7233846,
+		"disk.xvda1.sectorsWritten":   424712632,
+		"disk.xvda1.writeTime":        23987908,
+		"disk.xvda1.ioInProgress":     0,
+		"disk.xvda1.ioTime":           2355636,
+		"disk.xvda1.ioTimeWeighted":   24345740,
+		"disk.xvda1.discards":         0,
+		"disk.xvda1.discardsMerged":   0,
+		"disk.xvda1.sectorsDiscarded": 0,
+		"disk.xvda1.discardTime":      0,
+		"disk.loop0.reads":            15,
+		"disk.loop0.readsMerged":      0,
+		"disk.loop0.sectorsRead":      0,
+		"disk.loop0.readTime":         0,
+		"disk.loop0.writes":           0,
+		"disk.loop0.writesMerged":     0,
+		"disk.loop0.sectorsWritten":   0,
+		"disk.loop0.writeTime":        0,
+		"disk.loop0.ioInProgress":     0,
+		"disk.loop0.ioTime":           0,
+		"disk.loop0.ioTimeWeighted":   0,
+		"disk.loop0.discards":         0,
+		"disk.loop0.discardsMerged":   0,
+		"disk.loop0.sectorsDiscarded": 0,
+		"disk.loop0.discardTime":      0,
 	}
 	if !reflect.DeepEqual(result, expect) {
 		t.Errorf("result is not expected one: %+v", result)
@@ -156,7 +173,7 @@ func TestParseDiskStats_MoreFields(t *testing.T) {
 }
 
 func TestParseDiskStats_ShouldIgnoreIfAllFieldsAreZeroOrSpecificDeviceName(t *testing.T) {
-	g := &DiskGenerator{Interval: 1 * time.Second}
+	g := &DiskGenerator{}
 	out := []byte(`253       0 dm-0 2 0 40 0 314 0 2512 2136 0 236 2136
 253       1 dm-1 964 0 57886 944 74855 0 644512 5421192 0 1580 5422136
   7       0 loop0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0`)
@@ -171,3 +188,28 @@ func TestParseDiskStats_ShouldIgnoreIfAllFieldsAreZeroOrSpecificDeviceName(t *te
 		t.Errorf("result is not expected one: %+v", result)
 	}
 }
+
+func TestCalcDiskIO(t *testing.T) {
+	g := &DiskGenerator{}
+	prev, _ := g.parseDiskStats([]byte(`202       1 xvda1 1000 0 2000 500 100 0 4000 1000 0 10000 20000 10 0 80 30 0 0`), nil)
+	curr, _ := g.parseDiskStats([]byte(`202       1 xvda1 1100 0 2600 800 200 0 5200 1500 3 40000 80000 10 0 80 30 5 50`), nil)
+
+	values := calcDiskIO(curr, prev, 60*time.Second)
+	expect := metrics.Values{
+		"custom.disk.bytes.xvda1.read":             600 * 512 / 60,
+		"custom.disk.bytes.xvda1.write":            1200 * 512 / 60,
+		"custom.disk.bytes.xvda1.discard":          0,
+		"custom.disk.await.xvda1.read":             3,
+		"custom.disk.await.xvda1.write":            5,
+		"custom.disk.await.xvda1.discard":          0,
+		"custom.disk.await.xvda1.flush":            10,
+		"custom.disk.ops.xvda1.discard":            0,
+		"custom.disk.ops.xvda1.flush":              5.0 / 60,
+		"custom.disk.utilization.xvda1.percentage": 50,
+		"custom.disk.queue.xvda1.average":          1,
+		"custom.disk.queue.xvda1.in_flight":        3,
+	}
+	if !reflect.DeepEqual(values, expect) {
+		t.Errorf("result is not expected one: %+v", values)
+	}
+}
@@ -0,0 +1,182 @@
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mackerelio/mackerel-agent/metrics"
+	"github.com/mackerelio/mackerel-agent/util"
+	mkr "github.com/mackerelio/mackerel-client-go"
+)
+
+/*
+collect disk I/O metrics derived from /proc/diskstats
+
+`custom.disk.bytes.{device}.{read,write,discard}`: The bytes per second calculated from the sectors (512 bytes)
+
+`custom.disk.await.{device}.{read,write,discard,flush}`: The average time in milliseconds spent for an I/O request
+
+`custom.disk.ops.{device}.{discard,flush}`: The I/O requests per second (reads and writes are posted as `disk.{device}.{reads,writes}.delta`)
+
+`custom.disk.utilization.{device}.percentage`: The percentage of time the device was busy, calculated from ioTime
+
+`custom.disk.queue.{device}.in_flight`: The number of I/O requests in progress
+
+`custom.disk.queue.{device}.average`: The average number of I/O requests in progress, calculated from ioTimeWeighted
+
+The discard and flush metrics are generated only on Linux 4.18+ and 5.5+ respectively.
+The generator keeps the values of the previous collection, so it generates no values at the first collection.
+*/
+
+// DiskIOGenerator generates derived disk I/O metric values
+type DiskIOGenerator struct {
+	IgnoreRegexp  *regexp.Regexp
+	UseMountpoint bool
+
+	mu         sync.Mutex
+	prevValues metrics.Values
+	prevTime   time.Time
+}
+
+const diskSectorSize = 512
+
+// Generate derived disk I/O metric values
+func (g *DiskIOGenerator) Generate() (metrics.Values, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	disk := &DiskGenerator{IgnoreRegexp: g.IgnoreRegexp, UseMountpoint: g.UseMountpoint}
+	currValues, err := disk.collectDiskstatValues()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	prevValues, prevTime := g.prevValues, g.prevTime
+	g.prevValues, g.prevTime = currValues, now
+
+	if prevValues == nil {
+		return metrics.Values{}, nil
+	}
+	return calcDiskIO(currValues, prevValues, now.Sub(prevTime)), nil
+}
+
+func calcDiskIO(currValues, prevValues metrics.Values, elapsed time.Duration) metrics.Values {
+	ret := make(metrics.Values)
+	for name := range currValues {
+		if !strings.HasSuffix(name, ".ioTime") {
+			continue
+		}
+		device := strings.TrimSuffix(strings.TrimPrefix(name, "disk."), ".ioTime")
+		if _, ok := prevValues[name]; !ok {
+			continue
+		}
+		delta := func(metric string) (float64, bool) {
+			curr, ok1 := currValues["disk."+device+"."+metric]
+			prev, ok2 := prevValues["disk."+device+"."+metric]
+			if !ok1 || !ok2 {
+				return 0, false
+			}
+			return float64(util.DiffResettableCounter(uint64(curr), uint64(prev))), true
+		}
+		await := func(timeMetric, opsMetric string) (float64, bool) {
+			t, ok1 := delta(timeMetric)
+			n, ok2 := delta(opsMetric)
+			if !ok1 || !ok2 {
+				return 0, false
+			}
+			if n == 0 {
+				return 0, true
+			}
+			return t / n, true
+		}
+
+		for op, metric := range map[string]string{"read": "sectorsRead", "write": "sectorsWritten", "discard": "sectorsDiscarded"} {
+			if d, ok := delta(metric); ok {
+				ret["custom.disk.bytes."+device+"."+op] = d * diskSectorSize / elapsed.Seconds()
+			}
+		}
+		for op, cols := range map[string][2]string{
+			"read":    {"readTime", "reads"},
+			"write":   {"writeTime", "writes"},
+			"discard": {"discardTime", "discards"},
+			"flush":   {"flushTime", "flushes"},
+		} {
+			if v, ok := await(cols[0], cols[1]); ok {
+				ret["custom.disk.await."+device+"."+op] = v
+			}
+		}
+		for op, metric := range map[string]string{"discard": "discards", "flush": "flushes"} {
+			if d, ok := delta(metric); ok {
+				ret["custom.disk.ops."+device+"."+op] = d / elapsed.Seconds()
+			}
+		}
+
+		ms := float64(elapsed.Milliseconds())
+		if d, ok := delta("ioTime"); ok {
+			ret["custom.disk.utilization."+device+".percentage"] = min(d*100/ms, 100)
+		}
+		if d, ok := delta("ioTimeWeighted"); ok {
+			ret["custom.disk.queue."+device+".average"] = d / ms
+		}
+		ret["custom.disk.queue."+device+".in_flight"] = currValues["disk."+device+".ioInProgress"]
+	}
+	return ret
+}
+
+// CustomIdentifier for PluginGenerator interface
+func (g *DiskIOGenerator) CustomIdentifier() *string {
+	return nil
+}
+
+// PrepareGraphDefs for PluginGenerator interface
+func (g *DiskIOGenerator) PrepareGraphDefs() ([]*mkr.GraphDefsParam, error) {
+	return metrics.NewGraphDefsParam(map[string]metrics.CustomGraphDef{
+		"disk.bytes.#": {
+			Label: "Disk Bytes %1",
+			Unit:  "bytes/sec",
+			Metrics: []metrics.CustomGraphMetricDef{
+				{Name: "read", Label: "Read"},
+				{Name: "write", Label: "Write"},
+				{Name: "discard", Label: "Discard"},
+			},
+		},
+		"disk.await.#": {
+			Label: "Disk Await %1",
+			Unit:  "milliseconds",
+			Metrics: []metrics.CustomGraphMetricDef{
+				{Name: "read", Label: "Read"},
+				{Name: "write", Label: "Write"},
+				{Name: "discard", Label: "Discard"},
+				{Name: "flush", Label: "Flush"},
+			},
+		},
+		"disk.ops.#": {
+			Label: "Disk Discard/Flush IOPS %1",
+			Unit:  "iops",
+			Metrics: []metrics.CustomGraphMetricDef{
+				{Name: "discard", Label: "Discard"},
+				{Name: "flush", Label: "Flush"},
+			},
+		},
+		"disk.utilization.#": {
+			Label: "Disk Utilization %1",
+			Unit:  "percentage",
+			Metrics: []metrics.CustomGraphMetricDef{
+				{Name: "percentage", Label: "Utilization"},
+			},
+		},
+		"disk.queue.#": {
+			Label: "Disk Queue %1",
+			Unit:  "float",
+			Metrics: []metrics.CustomGraphMetricDef{
+				{Name: "in_flight", Label: "In Flight"},
+				{Name: "average", Label: "Average"},
+			},
+		},
+	}), nil
+}
@@ -0,0 +1,128 @@
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/mackerelio/golib/logging"
+	"github.com/mackerelio/mackerel-agent/metrics"
+	"github.com/mackerelio/mackerel-agent/util"
+	mkr "github.com/mackerelio/mackerel-client-go"
+)
+
+/*
+collect inode usage and read-only state of filesystems
+
+`custom.filesystem.{name}.{metric}`: The number of inodes retrieved by statfs(2)
+
+metric = "inodes_used", "inodes_free"
+
+`custom.filesystem_read_only.{name}`: 1 if the filesystem is mounted read-only, 0 otherwise
+
+name is the device name such as "sda1", or the mountpoint if UseMountpoint is enabled,
+same as `filesystem.{name}.*` metrics.
+
+graph: stacks `custom.filesystem.#.{metric}`, `custom.filesystem_read_only.*`
+*/
+
+// FilesystemStatsGenerator generates inode usage and read-only state of filesystems
+type FilesystemStatsGenerator struct {
+	IgnoreRegexp  *regexp.Regexp
+	UseMountpoint bool
+}
+
+var filesystemStatsLogger = logging.GetLogger("metrics.filesystemStats")
+
+// Generate inode usage and read-only state of filesystems
+func (g *FilesystemStatsGenerator) Generate() (metrics.Values, error) {
+	mounts, err := util.CollectMountInfo()
+	if err != nil {
+		filesystemStatsLogger.Errorf("Failed (skip these metrics): %s", err)
+		return nil, err
+	}
+	return g.collectValues(mounts, statfs), nil
+}
+
+type inodeStats struct {
+	Files uint64
+	Ffree uint64
+}
+
+func statfs(path string) (*inodeStats, error) {
+	st, err := util.Statfs(path)
+	if err != nil {
+		return nil, err
+	}
+	return &inodeStats{Files: st.Files, Ffree: st.Ffree}, nil
+}
+
+func (g *FilesystemStatsGenerator) collectValues(mounts []*util.MountInfo, statfs func(string) (*inodeStats, error)) metrics.Values {
+	ret := metrics.Values{}
+	for _, m := range mounts {
+		device := strings.TrimPrefix(m.Source, "/dev/")
+		if device == m.Source {
+			continue
+		}
+		if g.IgnoreRegexp != nil && g.IgnoreRegexp.MatchString(m.Source) {
+			continue
+		}
+		var name string
+		if g.UseMountpoint {
+			name = util.SanitizeMetricKey(m.Mountpoint)
+		} else {
+			name = util.SanitizeMetricKey(device)
+		}
+		// the device may be mounted on multiple mountpoints by bind mounts.
+		if _, ok := ret["custom.filesystem_read_only."+name]; ok {
+			continue
+		}
+
+		if m.ReadOnly() {
+			ret["custom.filesystem_read_only."+name] = 1
+		} else {
+			ret["custom.filesystem_read_only."+name] = 0
+		}
+
+		st, err := statfs(m.Mountpoint)
+		if err != nil {
+			filesystemStatsLogger.Warningf("Failed to statfs %s: %s", m.Mountpoint, err)
+			continue
+		}
+		// some filesystems such as btrfs do not have a fixed number of inodes.
+		if st.Files == 0 {
+			continue
+		}
+		ret["custom.filesystem."+name+".inodes_used"] = float64(st.Files - st.Ffree)
+		ret["custom.filesystem."+name+".inodes_free"] = float64(st.Ffree)
+	}
+	return ret
+}
+
+// CustomIdentifier for PluginGenerator interface
+func (g *FilesystemStatsGenerator) CustomIdentifier() *string {
+	return nil
+}
+
+// PrepareGraphDefs for PluginGenerator interface
+func (g *FilesystemStatsGenerator) PrepareGraphDefs() ([]*mkr.GraphDefsParam, error) {
+	return metrics.NewGraphDefsParam(map[string]metrics.CustomGraphDef{
+		"filesystem.#": {
+			Label: "Filesystem Inodes %1",
+			Unit:  "integer",
+			Metrics: []metrics.CustomGraphMetricDef{
+				{Name: "inodes_used", Label: "Used", Stacked: true},
+				{Name: "inodes_free", Label: "Free", Stacked: true},
+			},
+		},
+		"filesystem_read_only": {
+			Label: "Filesystem Read-only",
+			Unit:  "integer",
+			Metrics: []metrics.CustomGraphMetricDef{
+				{Name: "*", Label: "%1"},
+			},
+		},
+	}), nil
+}
@@ -0,0 +1,66 @@
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/mackerelio/mackerel-agent/metrics"
+	"github.com/mackerelio/mackerel-agent/util"
+)
+
+func TestFilesystemStatsGenerator(t *testing.T) {
+	g := &FilesystemStatsGenerator{}
+	if _, err := g.Generate(); err != nil {
+		t.Errorf("should not raise error: %v", err)
+	}
+}
+
+func TestFilesystemStatsGenerator_collectValues(t *testing.T) {
+	mounts := []*util.MountInfo{
+		{Source: "sysfs", Mountpoint: "/sys", FSType: "sysfs", Options: []string{"rw"}},
+		{Source: "/dev/sda1", Mountpoint: "/", FSType: "ext4", Options: []string{"rw"}},
+		{Source: "/dev/sda1", Mountpoint: "/bind", FSType: "ext4", Options: []string{"rw"}},
+		{Source: "/dev/sdb1", Mountpoint: "/data", FSType: "ext4", Options: []string{"rw", "ro"}},
+		{Source: "/dev/sdc1", Mountpoint: "/btrfs", FSType: "btrfs", Options: []string{"rw"}},
+		{Source: "/dev/sdd1", Mountpoint: "/ignored", FSType: "ext4", Options: []string{"rw"}},
+		{Source: "/dev/sde1", Mountpoint: "/stale", FSType: "ext4", Options: []string{"rw"}},
+	}
+	statfs := func(path string) (*inodeStats, error) {
+		switch path {
+		case "/":
+			return &inodeStats{Files: 1000, Ffree: 400}, nil
+		case "/data":
+			return &inodeStats{Files: 100, Ffree: 0}, nil
+		case "/btrfs":
+			return &inodeStats{}, nil
+		}
+		return nil, fmt.Errorf("failed to statfs %s", path)
+	}
+
+	g := &FilesystemStatsGenerator{IgnoreRegexp: regexp.MustCompile(`sdd`)}
+	values := g.collectValues(mounts, statfs)
+	expect := metrics.Values{
+		"custom.filesystem.sda1.inodes_used": 600,
+		"custom.filesystem.sda1.inodes_free": 400,
+		"custom.filesystem.sdb1.inodes_used": 100,
+		"custom.filesystem.sdb1.inodes_free": 0,
+		"custom.filesystem_read_only.sda1":   0,
+		"custom.filesystem_read_only.sdb1":   1,
+		"custom.filesystem_read_only.sdc1":   0,
+		"custom.filesystem_read_only.sde1":   0,
+	}
+	if !reflect.DeepEqual(values, expect) {
+		t.Errorf("values should be %+v but %+v", expect, values)
+	}
+
+	g = &FilesystemStatsGenerator{UseMountpoint: true}
+	values = g.collectValues(mounts[:2], statfs)
+	if _, ok := values["custom.filesystem._.inodes_used"]; !ok {
+		t.Errorf("values should be named by the mountpoint: %+v", values)
+	}
+}
@@ -0,0 +1,217 @@
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mackerelio/golib/logging"
+	"github.com/mackerelio/mackerel-agent/metrics"
+	"github.com/mackerelio/mackerel-agent/util"
+	mkr "github.com/mackerelio/mackerel-client-go"
+)
+
+/*
+collect temperatures and fan speeds from hardware monitoring chips
+
+`custom.hwmon.temp.{chip}.{sensor}`: The temperature in degrees Celsius retrieved from /sys/class/hwmon/hwmon*\/temp*_input
+
+`custom.hwmon.fan.{chip}.{sensor}`: The fan speed in RPM retrieved from /sys/class/hwmon/hwmon*\/fan*_input
+
+chip = the sanitized name of the chip in the name file (e.g. "coretemp"). If the name is duplicated, it is suffixed
+with the PCI address of the device, or the name of the device without PCI address (e.g. "nvme_0000_3d_00_0"),
+since the numbers of hwmon* may change on every boot.
+
+sensor = the sanitized label in temp*_label or fan*_label (e.g. "Package_id_0"), or "temp1" and "fan1" without the label
+
+ls /sys/class/hwmon/hwmon1 sample:
+	device  name  temp1_input  temp1_label  temp2_input  temp2_label  fan1_input
+
+readlink -f /sys/class/hwmon/hwmon1/device sample:
+	/sys/devices/pci0000:00/0000:00:1d.0/0000:3d:00.0/nvme/nvme0
+*/
+
+// HwmonGenerator generates temperatures and fan speeds
+type HwmonGenerator struct {
+	Path string // defaults to /sys/class/hwmon
+}
+
+var hwmonLogger = logging.GetLogger("metrics.hwmon")
+
+const sysClassHwmonPath = "/sys/class/hwmon"
+
+// Generate temperatures and fan speeds
+func (g *HwmonGenerator) Generate() (metrics.Values, error) {
+	dir := g.Path
+	if dir == "" {
+		dir = sysClassHwmonPath
+	}
+	values, err := collectHwmonValues(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		// virtual machines usually have no hardware monitoring chips
+		hwmonLogger.Debugf("No hardware monitoring chips: %s", err)
+		return metrics.Values{}, nil
+	}
+	if err != nil {
+		hwmonLogger.Errorf("Failed (skip these metrics): %s", err)
+		return nil, err
+	}
+	return values, nil
+}
+
+func collectHwmonValues(root string) (metrics.Values, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	// hwmon* are symbolic links to the directories of the devices.
+	chips := make(map[string]string)
+	names := make(map[string]int)
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), "hwmon") {
+			continue
+		}
+		dir := filepath.Join(root, entry.Name())
+		name, err := readHwmonFile(dir, "name")
+		if err != nil {
+			hwmonLogger.Debugf("Failed to read the name of %s: %s", dir, err)
+			name = entry.Name()
+		}
+		chips[dir] = util.SanitizeMetricKey(name)
+		names[chips[dir]]++
+	}
+
+	ret := make(metrics.Values)
+	for dir, chip := range chips {
+		if names[chip] > 1 {
+			chip += "_" + util.SanitizeMetricKey(hwmonDeviceName(dir))
+		}
+		for typ, sensors := range collectHwmonSensors(dir) {
+			for sensor, value := range sensors {
+				ret["custom.hwmon."+typ+"."+chip+"."+sensor] = value
+			}
+		}
+	}
+	return ret, nil
+}
+
+// e.g. "0000:3d:00.0"
+var pciAddressReg = regexp.MustCompile(`^[0-9a-f]{4}:[0-9a-f]{2}:[0-9a-f]{2}\.[0-9a-f]$`)
+
+// hwmonDeviceName returns the PCI address of the device of the chip, or the
+// name of the device if it is not on PCI bus. Unlike the number of hwmon*,
+// they are stable across reboots. It returns the base name of dir if the
+// chip has no device.
+func hwmonDeviceName(dir string) string {
+	device, err := filepath.EvalSymlinks(filepath.Join(dir, "device"))
+	if err != nil {
+		return filepath.Base(dir)
+	}
+	for p := device; p != "/" && p != "."; p = filepath.Dir(p) {
+		if pciAddressReg.MatchString(filepath.Base(p)) {
+			return filepath.Base(p)
+		}
+	}
+	return filepath.Base(device)
+}
+
+// hwmon sensor types and the divisors of their *_input values
+var hwmonSensorTypes = map[string]float64{
+	"temp": 1000, // millidegree Celsius
+	"fan":  1,    // RPM
+}
+
+// collectHwmonSensors returns the values of the sensors for each sensor type.
+func collectHwmonSensors(dir string) map[string]map[string]float64 {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		hwmonLogger.Warningf("Failed to read %s: %s", dir, err)
+		return nil
+	}
+	// sort to name the sensors with the same labels deterministically
+	inputs := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), "_input") {
+			inputs = append(inputs, strings.TrimSuffix(entry.Name(), "_input"))
+		}
+	}
+	sort.Strings(inputs)
+
+	ret := make(map[string]map[string]float64)
+	for _, raw := range inputs {
+		typ := strings.TrimRight(raw, "0123456789")
+		divisor, ok := hwmonSensorTypes[typ]
+		if !ok {
+			continue
+		}
+		out, err := readHwmonFile(dir, raw+"_input")
+		if err != nil {
+			// Reading a sensor may fail with ENODATA or EIO when it is not connected.
+			hwmonLogger.Debugf("Failed to read %s/%s_input: %s", dir, raw, err)
+			continue
+		}
+		v, err := strconv.ParseFloat(out, 64)
+		if err != nil {
+			continue
+		}
+		sensors := ret[typ]
+		if sensors == nil {
+			sensors = make(map[string]float64)
+			ret[typ] = sensors
+		}
+		sensor := raw
+		if label, err := readHwmonFile(dir, raw+"_label"); err == nil && label != "" {
+			if name := util.SanitizeMetricKey(label); !hasKey(sensors, name) {
+				sensor = name
+			}
+		}
+		sensors[sensor] = v / divisor
+	}
+	return ret
+}
+
+func hasKey(m map[string]float64, key string) bool {
+	_, ok := m[key]
+	return ok
+}
+
+func readHwmonFile(dir, name string) (string, error) {
+	out, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// CustomIdentifier for PluginGenerator interface
+func (g *HwmonGenerator) CustomIdentifier() *string {
+	return nil
+}
+
+// PrepareGraphDefs for PluginGenerator interface
+func (g *HwmonGenerator) PrepareGraphDefs() ([]*mkr.GraphDefsParam, error) {
+	return metrics.NewGraphDefsParam(map[string]metrics.CustomGraphDef{
+		"hwmon.temp.#": {
+			Label: "Temperature %1",
+			Unit:  "float",
+			Metrics: []metrics.CustomGraphMetricDef{
+				{Name: "*", Label: "%2"},
+			},
+		},
+		"hwmon.fan.#": {
+			Label: "Fan Speed %1",
+			Unit:  "float",
+			Metrics: []metrics.CustomGraphMetricDef{
+				{Name: "*", Label: "%2"},
+			},
+		},
+	}), nil
+}
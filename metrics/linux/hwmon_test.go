@@ -0,0 +1,119 @@
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mackerelio/mackerel-agent/metrics"
+)
+
+func TestHwmonGenerator(t *testing.T) {
+	g := &HwmonGenerator{}
+	values, err := g.Generate()
+	if err != nil {
+		t.Errorf("should not raise error: %v", err)
+	}
+	t.Logf("hwmon metrics: %+v", values)
+}
+
+// writeHwmonFiles writes files, whose contents with the prefix "->" are
+// the targets of symbolic links, under root.
+func writeHwmonFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		file := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if target, ok := strings.CutPrefix(content, "->"); ok {
+			if err := os.MkdirAll(filepath.Join(root, target), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Symlink(filepath.Join(root, target), file); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCollectHwmonValues(t *testing.T) {
+	root := t.TempDir()
+	writeHwmonFiles(t, root, map[string]string{
+		"hwmon0/name":        "acpitz\n",
+		"hwmon0/temp1_input": "27800\n",
+		"hwmon1/name":        "coretemp\n",
+		"hwmon1/temp1_input": "45000\n",
+		"hwmon1/temp1_label": "Package id 0\n",
+		"hwmon1/temp2_input": "43000\n",
+		"hwmon1/temp2_label": "Core 0\n",
+		"hwmon1/temp2_max":   "100000\n",
+		"hwmon2/name":        "nct6775\n",
+		"hwmon2/fan1_input":  "1200\n",
+		"hwmon2/fan2_input":  "980\n",
+		"hwmon2/fan2_label":  "CPU Fan\n",
+		"hwmon2/in0_input":   "1040\n",
+		"hwmon3/name":        "nvme\n",
+		"hwmon3/device":      "->devices/pci0000:00/0000:00:1d.0/0000:3d:00.0/nvme/nvme0",
+		"hwmon3/temp1_input": "38850\n",
+		"hwmon3/temp1_label": "Composite\n",
+		"hwmon3/temp2_input": "39850\n",
+		"hwmon3/temp2_label": "Composite\n",
+		"hwmon4/name":        "nvme\n",
+		"hwmon4/device":      "->devices/pci0000:00/0000:00:1c.0/0000:3e:00.0/nvme/nvme1",
+		"hwmon4/temp1_input": "40850\n",
+		"hwmon4/temp1_label": "Composite\n",
+		"hwmon5/name":        "drivetemp\n",
+		"hwmon5/device":      "->devices/virtual/scsi/0:0:0:0",
+		"hwmon5/temp1_input": "30000\n",
+		"hwmon6/name":        "drivetemp\n",
+		"hwmon6/temp1_input": "31000\n",
+	})
+	values, err := collectHwmonValues(root)
+	if err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+	expect := metrics.Values{
+		"custom.hwmon.temp.acpitz.temp1":                27.8,
+		"custom.hwmon.temp.coretemp.Package_id_0":       45,
+		"custom.hwmon.temp.coretemp.Core_0":             43,
+		"custom.hwmon.fan.nct6775.fan1":                 1200,
+		"custom.hwmon.fan.nct6775.CPU_Fan":              980,
+		"custom.hwmon.temp.nvme_0000_3d_00_0.Composite": 38.85,
+		"custom.hwmon.temp.nvme_0000_3d_00_0.temp2":     39.85,
+		"custom.hwmon.temp.nvme_0000_3e_00_0.Composite": 40.85,
+		"custom.hwmon.temp.drivetemp_0_0_0_0.temp1":     30,
+		"custom.hwmon.temp.drivetemp_hwmon6.temp1":      31,
+	}
+	if !reflect.DeepEqual(values, expect) {
+		t.Errorf("values should be %+v but %+v", expect, values)
+	}
+}
+
+func TestCollectHwmonValues_Symlink(t *testing.T) {
+	// /sys/class/hwmon/hwmon* are symbolic links to the device directories.
+	root := t.TempDir()
+	writeHwmonFiles(t, root, map[string]string{
+		"devices/platform/coretemp.0/hwmon/hwmon0/name":        "coretemp\n",
+		"devices/platform/coretemp.0/hwmon/hwmon0/temp1_input": "50000\n",
+		"class/hwmon/hwmon0": "->devices/platform/coretemp.0/hwmon/hwmon0",
+	})
+
+	values, err := (&HwmonGenerator{Path: filepath.Join(root, "class", "hwmon")}).Generate()
+	if err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+	expect := metrics.Values{"custom.hwmon.temp.coretemp.temp1": 50}
+	if !reflect.DeepEqual(values, expect) {
+		t.Errorf("values should be %+v but %+v", expect, values)
+	}
+}
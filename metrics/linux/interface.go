@@ -0,0 +1,202 @@
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mackerelio/golib/logging"
+	"github.com/mackerelio/mackerel-agent/metrics"
+	"github.com/mackerelio/mackerel-agent/util"
+	mkr "github.com/mackerelio/mackerel-client-go"
+)
+
+/*
+collect network interface packets, errors and drops
+
+`custom.interface.{metric}.{interface}.{rx,tx}`: The increased amount per second since the previous collection retrieved from /proc/net/dev
+
+metric = "packets", "errors", "drops"
+
+`custom.interface.multicast.{interface}.rx`: The received multicast packets per second
+
+`custom.interface.utilization.{interface}.{rx,tx}`: The percentage of bytes to the link speed retrieved from /sys/class/net/{interface}/speed
+
+cat /proc/net/dev sample:
+	Inter-|   Receive                                                |  Transmit
+	 face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+	    lo: 2776770   11307    0    0    0     0          0         0  2776770   11307    0    0    0     0       0          0
+	  eth0: 1215645    2751    0    0    0     0          0         0  1782404    4324    0    0    0   427       0          0
+*/
+
+// InterfaceStatsGenerator generates packets, errors and drops metric values of interfaces
+type InterfaceStatsGenerator struct {
+	IgnoreRegexp *regexp.Regexp
+
+	mu         sync.Mutex
+	prevValues map[string][]uint64
+	prevTime   time.Time
+}
+
+var interfaceStatsLogger = logging.GetLogger("metrics.interfaceStats")
+
+// indexes of the columns in /proc/net/dev
+const (
+	netDevRxBytes     = 0
+	netDevRxPackets   = 1
+	netDevRxErrors    = 2
+	netDevRxDrops     = 3
+	netDevRxMulticast = 7
+	netDevTxBytes     = 8
+	netDevTxPackets   = 9
+	netDevTxErrors    = 10
+	netDevTxDrops     = 11
+)
+
+// Generate packets, errors and drops metric values of interfaces
+func (g *InterfaceStatsGenerator) Generate() (metrics.Values, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	out, err := os.ReadFile("/proc/net/dev")
+	if err != nil {
+		interfaceStatsLogger.Errorf("Failed (skip these metrics): %s", err)
+		return nil, err
+	}
+	currValues := g.parseNetDev(out)
+	now := time.Now()
+	prevValues, prevTime := g.prevValues, g.prevTime
+	g.prevValues, g.prevTime = currValues, now
+
+	if prevValues == nil {
+		return metrics.Values{}, nil
+	}
+	return calcInterfaceStats(currValues, prevValues, now.Sub(prevTime), readLinkSpeed), nil
+}
+
+func (g *InterfaceStatsGenerator) parseNetDev(out []byte) map[string][]uint64 {
+	results := make(map[string][]uint64)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		kv := strings.SplitN(scanner.Text(), ":", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		fields := strings.Fields(kv[1])
+		if len(fields) < 16 {
+			continue
+		}
+		device := strings.TrimSpace(kv[0])
+		name := util.SanitizeMetricKey(device)
+		if device == "lo" || strings.HasPrefix(name, "veth") {
+			continue
+		}
+		if g.IgnoreRegexp != nil && g.IgnoreRegexp.MatchString(name) {
+			continue
+		}
+		values := make([]uint64, 16)
+		for i := range values {
+			v, err := strconv.ParseUint(fields[i], 10, 64)
+			if err != nil {
+				interfaceStatsLogger.Warningf("Failed to parse interface metrics of %s: %s", device, err)
+				break
+			}
+			values[i] = v
+		}
+		results[device] = values
+	}
+	return results
+}
+
+// readLinkSpeed returns the link speed of the interface in Mbps, or 0 if unknown.
+func readLinkSpeed(device string) float64 {
+	out, err := os.ReadFile(filepath.Join(util.SysClassNetPath, device, "speed"))
+	if err != nil {
+		return 0
+	}
+	speed, err := strconv.ParseFloat(strings.TrimSpace(string(out)), 64)
+	if err != nil || speed <= 0 {
+		return 0
+	}
+	return speed
+}
+
+func calcInterfaceStats(currValues, prevValues map[string][]uint64, elapsed time.Duration, linkSpeed func(string) float64) metrics.Values {
+	ret := make(metrics.Values)
+	seconds := elapsed.Seconds()
+	for device, curr := range currValues {
+		prev, ok := prevValues[device]
+		if !ok {
+			continue
+		}
+		rate := func(i int) float64 {
+			return float64(util.DiffResettableCounter(curr[i], prev[i])) / seconds
+		}
+		name := util.SanitizeMetricKey(device)
+		ret["custom.interface.packets."+name+".rx"] = rate(netDevRxPackets)
+		ret["custom.interface.packets."+name+".tx"] = rate(netDevTxPackets)
+		ret["custom.interface.errors."+name+".rx"] = rate(netDevRxErrors)
+		ret["custom.interface.errors."+name+".tx"] = rate(netDevTxErrors)
+		ret["custom.interface.drops."+name+".rx"] = rate(netDevRxDrops)
+		ret["custom.interface.drops."+name+".tx"] = rate(netDevTxDrops)
+		ret["custom.interface.multicast."+name+".rx"] = rate(netDevRxMulticast)
+
+		if speed := linkSpeed(device); speed > 0 {
+			bitsPerSecond := speed * 1000 * 1000
+			ret["custom.interface.utilization."+name+".rx"] = rate(netDevRxBytes) * 8 * 100 / bitsPerSecond
+			ret["custom.interface.utilization."+name+".tx"] = rate(netDevTxBytes) * 8 * 100 / bitsPerSecond
+		}
+	}
+	return ret
+}
+
+// CustomIdentifier for PluginGenerator interface
+func (g *InterfaceStatsGenerator) CustomIdentifier() *string {
+	return nil
+}
+
+// PrepareGraphDefs for PluginGenerator interface
+func (g *InterfaceStatsGenerator) PrepareGraphDefs() ([]*mkr.GraphDefsParam, error) {
+	rxtx := []metrics.CustomGraphMetricDef{
+		{Name: "rx", Label: "Received"},
+		{Name: "tx", Label: "Transmitted"},
+	}
+	return metrics.NewGraphDefsParam(map[string]metrics.CustomGraphDef{
+		"interface.packets.#": {
+			Label:   "Interface Packets %1",
+			Unit:    "float",
+			Metrics: rxtx,
+		},
+		"interface.errors.#": {
+			Label:   "Interface Errors %1",
+			Unit:    "float",
+			Metrics: rxtx,
+		},
+		"interface.drops.#": {
+			Label:   "Interface Drops %1",
+			Unit:    "float",
+			Metrics: rxtx,
+		},
+		"interface.multicast.#": {
+			Label: "Interface Multicast Packets %1",
+			Unit:  "float",
+			Metrics: []metrics.CustomGraphMetricDef{
+				{Name: "rx", Label: "Received"},
+			},
+		},
+		"interface.utilization.#": {
+			Label:   "Interface Utilization %1",
+			Unit:    "percentage",
+			Metrics: rxtx,
+		},
+	}), nil
+}
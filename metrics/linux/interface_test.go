@@ -0,0 +1,66 @@
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/mackerelio/mackerel-agent/metrics"
+)
+
+func TestInterfaceStatsGenerator(t *testing.T) {
+	g := &InterfaceStatsGenerator{}
+	values, err := g.Generate()
+	if err != nil {
+		t.Errorf("should not raise error: %v", err)
+	}
+	if len(values) != 0 {
+		t.Errorf("first collection should not generate values: %v", values)
+	}
+
+	time.Sleep(1 * time.Second)
+	if _, err := g.Generate(); err != nil {
+		t.Errorf("should not raise error: %v", err)
+	}
+}
+
+func TestCalcInterfaceStats(t *testing.T) {
+	g := &InterfaceStatsGenerator{IgnoreRegexp: regexp.MustCompile(`^docker`)}
+	prev := g.parseNetDev([]byte(`Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo: 2776770   11307    0    0    0     0          0         0  2776770   11307    0    0    0     0       0          0
+  eth0: 1000000    2000    0    0    0     0          0        10  2000000    4000    0    0    0   427       0          0
+docker0: 1000000    2000    0    0    0     0          0        10  2000000    4000    0    0    0   427       0          0
+vethabc: 1000000    2000    0    0    0     0          0        10  2000000    4000    0    0    0   427       0          0`))
+	curr := g.parseNetDev([]byte(`Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo: 2776770   11307    0    0    0     0          0         0  2776770   11307    0    0    0     0       0          0
+  eth0: 8500000    2600   60  120    0     0          0        70  17000000    4600    0   6    0   427       0          0
+docker0: 1000000    2000    0    0    0     0          0        10  2000000    4000    0    0    0   427       0          0
+vethabc: 1000000    2000    0    0    0     0          0        10  2000000    4000    0    0    0   427       0          0`))
+
+	values := calcInterfaceStats(curr, prev, 60*time.Second, func(device string) float64 {
+		if device == "eth0" {
+			return 10 // Mbps
+		}
+		return 0
+	})
+	expect := metrics.Values{
+		"custom.interface.packets.eth0.rx":     10,
+		"custom.interface.packets.eth0.tx":     10,
+		"custom.interface.errors.eth0.rx":      1,
+		"custom.interface.errors.eth0.tx":      0,
+		"custom.interface.drops.eth0.rx":       2,
+		"custom.interface.drops.eth0.tx":       0.1,
+		"custom.interface.multicast.eth0.rx":   1,
+		"custom.interface.utilization.eth0.rx": 10,
+		"custom.interface.utilization.eth0.tx": 20,
+	}
+	if !reflect.DeepEqual(values, expect) {
+		t.Errorf("result is not expected one: %+v", values)
+	}
+}
@@ -0,0 +1,201 @@
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mackerelio/golib/logging"
+	"github.com/mackerelio/mackerel-agent/metrics"
+	"github.com/mackerelio/mackerel-agent/util"
+	mkr "github.com/mackerelio/mackerel-client-go"
+)
+
+/*
+collect kernel activity
+
+`custom.kernel.activity.{context_switches,interrupts}`: The context switches/interrupts per second retrieved from /proc/stat (ctxt, intr)
+
+`custom.kernel.forks.processes`: The processes created per second (processes)
+
+`custom.kernel.procs.{running,blocked}`: The number of processes running/blocked on I/O (procs_running, procs_blocked)
+
+`custom.kernel.files.{allocated,max}`: The number of allocated file handles and the maximum retrieved from /proc/sys/fs/file-nr (max is not generated if unlimited)
+
+cat /proc/stat sample (except cpu lines):
+	intr 114930548 113199788 3 0 5 263 0 4 [... lots more numbers ...]
+	ctxt 1990473
+	btime 1062191376
+	processes 2915
+	procs_running 1
+	procs_blocked 0
+
+cat /proc/sys/fs/file-nr sample:
+	1824	0	9223372036854775807
+*/
+
+// KernelGenerator generates kernel activity metric values
+type KernelGenerator struct {
+	mu         sync.Mutex
+	prevValues map[string]uint64
+	prevTime   time.Time
+}
+
+var kernelLogger = logging.GetLogger("metrics.kernel")
+
+// counters in /proc/stat
+var kernelRates = map[string]string{
+	"ctxt":      "custom.kernel.activity.context_switches",
+	"intr":      "custom.kernel.activity.interrupts",
+	"processes": "custom.kernel.forks.processes",
+}
+
+// gauges in /proc/stat
+var kernelGauges = map[string]string{
+	"procs_running": "custom.kernel.procs.running",
+	"procs_blocked": "custom.kernel.procs.blocked",
+}
+
+// Generate kernel activity metric values
+func (g *KernelGenerator) Generate() (metrics.Values, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	out, err := os.ReadFile("/proc/stat")
+	if err != nil {
+		kernelLogger.Errorf("Failed (skip these metrics): %s", err)
+		return nil, err
+	}
+	currValues := parseProcStat(out)
+	now := time.Now()
+	prevValues, prevTime := g.prevValues, g.prevTime
+	g.prevValues, g.prevTime = currValues, now
+
+	ret := calcKernel(currValues, prevValues, now.Sub(prevTime))
+
+	fileNr, err := os.ReadFile("/proc/sys/fs/file-nr")
+	if err != nil {
+		kernelLogger.Warningf("Failed to read file-nr: %s", err)
+		return ret, nil
+	}
+	allocated, max, err := parseFileNr(fileNr)
+	if err != nil {
+		kernelLogger.Warningf("Failed to parse file-nr: %s", err)
+		return ret, nil
+	}
+	ret["custom.kernel.files.allocated"] = float64(allocated)
+	// systemd sets fs.file-max to LONG_MAX, which means unlimited.
+	if max < math.MaxInt64 {
+		ret["custom.kernel.files.max"] = float64(max)
+	}
+	return ret, nil
+}
+
+// parseProcStat returns the first value of each line in /proc/stat except cpu lines.
+func parseProcStat(out []byte) map[string]uint64 {
+	values := make(map[string]uint64)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "cpu") {
+			continue
+		}
+		v, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		values[fields[0]] = v
+	}
+	return values
+}
+
+func calcKernel(currValues, prevValues map[string]uint64, elapsed time.Duration) metrics.Values {
+	ret := make(metrics.Values)
+	for name, key := range kernelGauges {
+		if v, ok := currValues[name]; ok {
+			ret[key] = float64(v)
+		}
+	}
+	if prevValues == nil || elapsed <= 0 {
+		return ret
+	}
+	for name, key := range kernelRates {
+		curr, ok := currValues[name]
+		if !ok {
+			continue
+		}
+		if prev, ok := prevValues[name]; ok {
+			ret[key] = float64(util.DiffResettableCounter(curr, prev)) / elapsed.Seconds()
+		}
+	}
+	return ret
+}
+
+// parseFileNr returns the number of allocated file handles and the maximum.
+// The number of allocated but unused file handles is always 0 since Linux 2.6.
+func parseFileNr(out []byte) (uint64, uint64, error) {
+	fields := strings.Fields(string(out))
+	if len(fields) != 3 {
+		return 0, 0, fmt.Errorf("unexpected format: %q", out)
+	}
+	allocated, err := strconv.ParseUint(fields[0], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	max, err := strconv.ParseUint(fields[2], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	return allocated, max, nil
+}
+
+// CustomIdentifier for PluginGenerator interface
+func (g *KernelGenerator) CustomIdentifier() *string {
+	return nil
+}
+
+// PrepareGraphDefs for PluginGenerator interface
+func (g *KernelGenerator) PrepareGraphDefs() ([]*mkr.GraphDefsParam, error) {
+	return metrics.NewGraphDefsParam(map[string]metrics.CustomGraphDef{
+		"kernel.activity": {
+			Label: "Kernel Activity",
+			Unit:  "float",
+			Metrics: []metrics.CustomGraphMetricDef{
+				{Name: "context_switches", Label: "Context Switches"},
+				{Name: "interrupts", Label: "Interrupts"},
+			},
+		},
+		"kernel.forks": {
+			Label: "Process Creations",
+			Unit:  "float",
+			Metrics: []metrics.CustomGraphMetricDef{
+				{Name: "processes", Label: "Processes"},
+			},
+		},
+		"kernel.procs": {
+			Label: "Processes",
+			Unit:  "integer",
+			Metrics: []metrics.CustomGraphMetricDef{
+				{Name: "running", Label: "Running"},
+				{Name: "blocked", Label: "Blocked"},
+			},
+		},
+		"kernel.files": {
+			Label: "File Handles",
+			Unit:  "integer",
+			Metrics: []metrics.CustomGraphMetricDef{
+				{Name: "allocated", Label: "Allocated"},
+				{Name: "max", Label: "Max"},
+			},
+		},
+	}), nil
+}
@@ -0,0 +1,93 @@
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mackerelio/mackerel-agent/metrics"
+)
+
+func TestKernelGenerator(t *testing.T) {
+	g := &KernelGenerator{}
+	values, err := g.Generate()
+	if err != nil {
+		t.Errorf("should not raise error: %v", err)
+	}
+	if _, ok := values["custom.kernel.activity.context_switches"]; ok {
+		t.Errorf("rates should not be generated at the first collection: %+v", values)
+	}
+
+	time.Sleep(1 * time.Second)
+	values, err = g.Generate()
+	if err != nil {
+		t.Errorf("should not raise error: %v", err)
+	}
+	for _, name := range []string{
+		"custom.kernel.activity.context_switches",
+		"custom.kernel.activity.interrupts",
+		"custom.kernel.forks.processes",
+		"custom.kernel.procs.running",
+		"custom.kernel.procs.blocked",
+		"custom.kernel.files.allocated",
+	} {
+		if _, ok := values[name]; !ok {
+			t.Errorf("kernel metrics should have %s: %+v", name, values)
+		}
+	}
+}
+
+func TestCalcKernel(t *testing.T) {
+	prev := parseProcStat([]byte(`cpu  2255 34 2290 22625563 6290 127 456 0 0 0
+cpu0 1132 34 1441 11311718 3675 127 438 0 0 0
+intr 114930548 113199788 3 0 5 263 0 4
+ctxt 1990473
+btime 1062191376
+processes 2915
+procs_running 1
+procs_blocked 0
+`))
+	curr := parseProcStat([]byte(`cpu  2355 34 2390 22625663 6290 127 456 0 0 0
+cpu0 1182 34 1491 11311768 3675 127 438 0 0 0
+intr 114936548 113205788 3 0 5 263 0 4
+ctxt 2002473
+btime 1062191376
+processes 3035
+procs_running 3
+procs_blocked 2
+`))
+
+	values := calcKernel(curr, prev, 60*time.Second)
+	expect := metrics.Values{
+		"custom.kernel.activity.context_switches": 200,
+		"custom.kernel.activity.interrupts":       100,
+		"custom.kernel.forks.processes":           2,
+		"custom.kernel.procs.running":             3,
+		"custom.kernel.procs.blocked":             2,
+	}
+	if !reflect.DeepEqual(values, expect) {
+		t.Errorf("values should be %+v but %+v", expect, values)
+	}
+
+	values = calcKernel(curr, nil, 0)
+	if len(values) != 2 {
+		t.Errorf("only gauges should be generated without previous values: %+v", values)
+	}
+}
+
+func TestParseFileNr(t *testing.T) {
+	allocated, max, err := parseFileNr([]byte("1824\t0\t9223372036854775807\n"))
+	if err != nil {
+		t.Errorf("should not raise error: %v", err)
+	}
+	if allocated != 1824 || max != 9223372036854775807 {
+		t.Errorf("unexpected values: %d, %d", allocated, max)
+	}
+
+	if _, _, err := parseFileNr([]byte("1824\n")); err == nil {
+		t.Error("should raise error for malformed file-nr")
+	}
+}
@@ -0,0 +1,166 @@
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/mackerelio/golib/logging"
+	"github.com/mackerelio/mackerel-agent/metrics"
+	"github.com/mackerelio/mackerel-agent/util"
+	mkr "github.com/mackerelio/mackerel-client-go"
+)
+
+/*
+collect Pressure Stall Information (PSI)
+
+`custom.pressure.avg.{resource}.{some,full}_{avg10,avg60}`: The percentage of time some (or all) tasks were stalled on the resource in the last 10 (or 60) seconds
+
+`custom.pressure.stall.{resource}.{some,full}`: The percentage of time stalled since the previous collection, calculated from total
+
+resource = "cpu", "memory", "io"
+
+The resources are skipped on kernels without PSI (Linux 4.20+ with CONFIG_PSI).
+
+cat /proc/pressure/memory sample:
+	some avg10=0.00 avg60=0.00 avg300=0.00 total=0
+	full avg10=0.00 avg60=0.00 avg300=0.00 total=0
+*/
+
+// PressureGenerator generates PSI metric values
+type PressureGenerator struct {
+	mu         sync.Mutex
+	prevTotals map[string]uint64
+	prevTime   time.Time
+}
+
+var pressureLogger = logging.GetLogger("metrics.pressure")
+
+const procPressurePath = "/proc/pressure"
+
+var pressureResources = []string{"cpu", "memory", "io"}
+
+type pressureLine struct {
+	Avg10 float64
+	Avg60 float64
+	Total uint64 // microseconds
+}
+
+// Generate PSI metric values
+func (g *PressureGenerator) Generate() (metrics.Values, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	pressures := make(map[string]map[string]*pressureLine)
+	for _, resource := range pressureResources {
+		out, err := os.ReadFile(filepath.Join(procPressurePath, resource))
+		if err != nil {
+			// /proc/pressure does not exist, or PSI is disabled by psi=0.
+			if errors.Is(err, os.ErrNotExist) || errors.Is(err, syscall.EOPNOTSUPP) {
+				pressureLogger.Debugf("PSI is not available: %s", err)
+				continue
+			}
+			pressureLogger.Warningf("Failed to read pressure of %s: %s", resource, err)
+			continue
+		}
+		pressures[resource] = parsePressure(out)
+	}
+
+	now := time.Now()
+	prevTotals, prevTime := g.prevTotals, g.prevTime
+	g.prevTotals, g.prevTime = make(map[string]uint64), now
+	return calcPressure(pressures, prevTotals, g.prevTotals, now.Sub(prevTime)), nil
+}
+
+func parsePressure(out []byte) map[string]*pressureLine {
+	lines := make(map[string]*pressureLine)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		line := &pressureLine{}
+		for _, field := range fields[1:] {
+			k, v, ok := strings.Cut(field, "=")
+			if !ok {
+				continue
+			}
+			var err error
+			switch k {
+			case "avg10":
+				line.Avg10, err = strconv.ParseFloat(v, 64)
+			case "avg60":
+				line.Avg60, err = strconv.ParseFloat(v, 64)
+			case "total":
+				line.Total, err = strconv.ParseUint(v, 10, 64)
+			}
+			if err != nil {
+				pressureLogger.Warningf("Failed to parse pressure: %s", err)
+			}
+		}
+		lines[fields[0]] = line
+	}
+	return lines
+}
+
+// calcPressure calculates metric values from pressures, and stores the totals to currTotals.
+func calcPressure(pressures map[string]map[string]*pressureLine, prevTotals, currTotals map[string]uint64, elapsed time.Duration) metrics.Values {
+	ret := make(metrics.Values)
+	for resource, lines := range pressures {
+		for _, kind := range []string{"some", "full"} {
+			line, ok := lines[kind]
+			if !ok {
+				continue
+			}
+			ret["custom.pressure.avg."+resource+"."+kind+"_avg10"] = line.Avg10
+			ret["custom.pressure.avg."+resource+"."+kind+"_avg60"] = line.Avg60
+
+			key := "custom.pressure.stall." + resource + "." + kind
+			currTotals[key] = line.Total
+			if prev, ok := prevTotals[key]; ok && elapsed > 0 {
+				ret[key] = float64(util.DiffResettableCounter(line.Total, prev)) * 100 / float64(elapsed.Microseconds())
+			}
+		}
+	}
+	return ret
+}
+
+// CustomIdentifier for PluginGenerator interface
+func (g *PressureGenerator) CustomIdentifier() *string {
+	return nil
+}
+
+// PrepareGraphDefs for PluginGenerator interface
+func (g *PressureGenerator) PrepareGraphDefs() ([]*mkr.GraphDefsParam, error) {
+	return metrics.NewGraphDefsParam(map[string]metrics.CustomGraphDef{
+		"pressure.avg.#": {
+			Label: "Pressure Stall Average %1",
+			Unit:  "percentage",
+			Metrics: []metrics.CustomGraphMetricDef{
+				{Name: "some_avg10", Label: "Some (10s)"},
+				{Name: "some_avg60", Label: "Some (60s)"},
+				{Name: "full_avg10", Label: "Full (10s)"},
+				{Name: "full_avg60", Label: "Full (60s)"},
+			},
+		},
+		"pressure.stall.#": {
+			Label: "Pressure Stall Time %1",
+			Unit:  "percentage",
+			Metrics: []metrics.CustomGraphMetricDef{
+				{Name: "some", Label: "Some"},
+				{Name: "full", Label: "Full"},
+			},
+		},
+	}), nil
+}
@@ -0,0 +1,57 @@
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mackerelio/mackerel-agent/metrics"
+)
+
+func TestPressureGenerator(t *testing.T) {
+	g := &PressureGenerator{}
+	if _, err := g.Generate(); err != nil {
+		t.Errorf("should not raise error: %v", err)
+	}
+	values, err := g.Generate()
+	if err != nil {
+		t.Errorf("should not raise error: %v", err)
+	}
+	t.Logf("pressure metrics: %+v", values)
+}
+
+func TestCalcPressure(t *testing.T) {
+	pressures := map[string]map[string]*pressureLine{
+		"cpu": parsePressure([]byte(`some avg10=1.76 avg60=4.17 avg300=2.66 total=63309240
+`)),
+		"memory": parsePressure([]byte(`some avg10=0.50 avg60=0.25 avg300=0.00 total=3000000
+full avg10=0.10 avg60=0.05 avg300=0.00 total=1500000
+`)),
+	}
+	prevTotals := map[string]uint64{
+		"custom.pressure.stall.memory.some": 0,
+		"custom.pressure.stall.memory.full": 0,
+	}
+	currTotals := map[string]uint64{}
+
+	values := calcPressure(pressures, prevTotals, currTotals, 60*time.Second)
+	expect := metrics.Values{
+		"custom.pressure.avg.cpu.some_avg10":    1.76,
+		"custom.pressure.avg.cpu.some_avg60":    4.17,
+		"custom.pressure.avg.memory.some_avg10": 0.5,
+		"custom.pressure.avg.memory.some_avg60": 0.25,
+		"custom.pressure.avg.memory.full_avg10": 0.1,
+		"custom.pressure.avg.memory.full_avg60": 0.05,
+		"custom.pressure.stall.memory.some":     5,
+		"custom.pressure.stall.memory.full":     2.5,
+	}
+	if !reflect.DeepEqual(values, expect) {
+		t.Errorf("values should be %+v but %+v", expect, values)
+	}
+	if currTotals["custom.pressure.stall.cpu.some"] != 63309240 {
+		t.Errorf("totals should be stored: %+v", currTotals)
+	}
+}
@@ -0,0 +1,124 @@
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"github.com/mackerelio/golib/logging"
+	"github.com/mackerelio/mackerel-agent/metrics"
+	"github.com/mackerelio/mackerel-agent/util"
+	mkr "github.com/mackerelio/mackerel-client-go"
+)
+
+/*
+collect the health of software RAID (md) arrays and ZFS pools
+
+`custom.md.disks.{array}.{total,active,failed}`: The number of disks of the array, retrieved from /proc/mdstat
+
+`custom.md.degraded.{array}`: 1 if the array is missing any disks, otherwise 0 (inactive arrays such as IMSM and DDF containers are not degraded)
+
+`custom.md.sync.{array}`: The progress of resync, recovery, reshape or check in percentage (only while in progress)
+
+`custom.zfs.online.{pool}`: 1 if the state of the pool is ONLINE, otherwise 0, retrieved from /proc/spl/kstat/zfs/{pool}/state
+
+cat /proc/mdstat sample:
+	md1 : active raid5 sdc1[3] sdb2[1] sda2[0](F)
+	      2095104 blocks super 1.2 level 5, 512k chunk, algorithm 2 [3/2] [_UU]
+	      [=>...................]  recovery =  8.5% (89376/1047552) finish=0.5min speed=29792K/sec
+*/
+
+// RAIDGenerator generates the health of software RAID arrays and ZFS pools
+type RAIDGenerator struct {
+	MdstatPath string // defaults to util.MdstatPath
+	ZFSPath    string // defaults to util.ZFSKstatPath
+}
+
+var raidLogger = logging.GetLogger("metrics.raid")
+
+// Generate the health of software RAID arrays and ZFS pools
+func (g *RAIDGenerator) Generate() (metrics.Values, error) {
+	mdstatPath, zfsPath := g.MdstatPath, g.ZFSPath
+	if mdstatPath == "" {
+		mdstatPath = util.MdstatPath
+	}
+	if zfsPath == "" {
+		zfsPath = util.ZFSKstatPath
+	}
+	arrays, err := util.ReadMdstat(mdstatPath)
+	if err != nil {
+		raidLogger.Errorf("Failed to read %s (skip these metrics): %s", mdstatPath, err)
+		return nil, err
+	}
+	pools, err := util.ReadZpoolStates(zfsPath)
+	if err != nil {
+		raidLogger.Errorf("Failed to read %s (skip these metrics): %s", zfsPath, err)
+		return nil, err
+	}
+	return calcRAID(arrays, pools), nil
+}
+
+func calcRAID(arrays []*util.MdArray, pools map[string]string) metrics.Values {
+	ret := make(metrics.Values)
+	for _, a := range arrays {
+		name := util.SanitizeMetricKey(a.Name)
+		ret["custom.md.disks."+name+".total"] = float64(a.Disks)
+		ret["custom.md.disks."+name+".active"] = float64(a.ActiveDisks)
+		ret["custom.md.disks."+name+".failed"] = float64(a.FailedDisks)
+		ret["custom.md.degraded."+name] = boolToFloat(a.Degraded())
+		if a.SyncAction != "" {
+			ret["custom.md.sync."+name] = a.SyncPercent
+		}
+	}
+	for pool, state := range pools {
+		ret["custom.zfs.online."+util.SanitizeMetricKey(pool)] = boolToFloat(state == "ONLINE")
+	}
+	return ret
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// CustomIdentifier for PluginGenerator interface
+func (g *RAIDGenerator) CustomIdentifier() *string {
+	return nil
+}
+
+// PrepareGraphDefs for PluginGenerator interface
+func (g *RAIDGenerator) PrepareGraphDefs() ([]*mkr.GraphDefsParam, error) {
+	return metrics.NewGraphDefsParam(map[string]metrics.CustomGraphDef{
+		"md.disks.#": {
+			Label: "MD Array Disks %1",
+			Unit:  "integer",
+			Metrics: []metrics.CustomGraphMetricDef{
+				{Name: "total", Label: "Total"},
+				{Name: "active", Label: "Active"},
+				{Name: "failed", Label: "Failed"},
+			},
+		},
+		"md.degraded": {
+			Label: "MD Array Degraded",
+			Unit:  "integer",
+			Metrics: []metrics.CustomGraphMetricDef{
+				{Name: "*", Label: "%1"},
+			},
+		},
+		"md.sync": {
+			Label: "MD Array Sync Progress",
+			Unit:  "percentage",
+			Metrics: []metrics.CustomGraphMetricDef{
+				{Name: "*", Label: "%1"},
+			},
+		},
+		"zfs.online": {
+			Label: "ZFS Pool Online",
+			Unit:  "integer",
+			Metrics: []metrics.CustomGraphMetricDef{
+				{Name: "*", Label: "%1"},
+			},
+		},
+	}), nil
+}
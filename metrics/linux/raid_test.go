@@ -0,0 +1,50 @@
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mackerelio/mackerel-agent/metrics"
+	"github.com/mackerelio/mackerel-agent/util"
+)
+
+func TestRAIDGenerator(t *testing.T) {
+	g := &RAIDGenerator{}
+	values, err := g.Generate()
+	if err != nil {
+		t.Errorf("should not raise error: %v", err)
+	}
+	t.Logf("raid metrics: %+v", values)
+}
+
+func TestCalcRAID(t *testing.T) {
+	arrays := []*util.MdArray{
+		{Name: "md0", State: "active", Level: "raid1", Disks: 2, ActiveDisks: 2},
+		{Name: "md1", State: "active", Level: "raid5", Disks: 3, ActiveDisks: 2, FailedDisks: 1, SyncAction: "recovery", SyncPercent: 8.5},
+		{Name: "md127", State: "inactive"},
+	}
+	pools := map[string]string{"tank": "ONLINE", "backup": "DEGRADED"}
+	expected := metrics.Values{
+		"custom.md.disks.md0.total":    2,
+		"custom.md.disks.md0.active":   2,
+		"custom.md.disks.md0.failed":   0,
+		"custom.md.degraded.md0":       0,
+		"custom.md.disks.md1.total":    3,
+		"custom.md.disks.md1.active":   2,
+		"custom.md.disks.md1.failed":   1,
+		"custom.md.degraded.md1":       1,
+		"custom.md.sync.md1":           8.5,
+		"custom.md.disks.md127.total":  0,
+		"custom.md.disks.md127.active": 0,
+		"custom.md.disks.md127.failed": 0,
+		"custom.md.degraded.md127":     0,
+		"custom.zfs.online.tank":       1,
+		"custom.zfs.online.backup":     0,
+	}
+	if values := calcRAID(arrays, pools); !reflect.DeepEqual(values, expected) {
+		t.Errorf("values should be %v but %v", expected, values)
+	}
+}
@@ -0,0 +1,270 @@
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mackerelio/golib/logging"
+	"github.com/mackerelio/mackerel-agent/metrics"
+	"github.com/mackerelio/mackerel-agent/util"
+	mkr "github.com/mackerelio/mackerel-client-go"
+)
+
+/*
+collect resource usage of each systemd unit from cgroup v2
+
+`custom.systemd.{unit}.cpu.{user,system}`: The CPU time of the unit since the previous collection as percentage, retrieved from cpu.stat
+
+`custom.systemd.{unit}.memory.{current,peak}`: The memory usage in bytes, retrieved from memory.current and memory.peak (Linux 5.19+)
+
+`custom.systemd.{unit}.io.{read,write}`: The bytes read/written per second on all devices, retrieved from io.stat
+
+`custom.systemd.{unit}.pids.current`: The number of tasks, retrieved from pids.current
+
+unit = the sanitized name of the unit under /sys/fs/cgroup/system.slice, including the nested slices
+       such as system-getty.slice, without ".service" suffix (e.g. "getty_tty1" for getty@tty1.service)
+
+cat io.stat sample:
+	8:0 rbytes=90430464 wbytes=299008000 rios=8950 wios=12252 dbytes=0 dios=0
+*/
+
+// SystemdGenerator generates resource usage of each systemd unit
+type SystemdGenerator struct {
+	IncludeRegexp *regexp.Regexp
+	ExcludeRegexp *regexp.Regexp
+	Root          string // defaults to util.CgroupRoot
+
+	mu         sync.Mutex
+	prevValues map[string]map[string]uint64
+	prevTime   time.Time
+}
+
+var systemdLogger = logging.GetLogger("metrics.systemd")
+
+// Generate resource usage of each systemd unit
+func (g *SystemdGenerator) Generate() (metrics.Values, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	root := g.Root
+	if root == "" {
+		root = util.CgroupRoot
+	}
+	ret := make(metrics.Values)
+	currValues := make(map[string]map[string]uint64)
+	err := g.walkSlice(root, "/system.slice", func(unit string, cgroup *util.Cgroup) {
+		stats := collectSystemdUnitStats(cgroup)
+		if len(stats) == 0 {
+			return
+		}
+		name := util.SanitizeMetricKey(strings.TrimSuffix(unit, ".service"))
+		currValues[name] = stats
+		for k, v := range systemdGauges(name, stats) {
+			ret[k] = v
+		}
+	})
+	if err != nil {
+		systemdLogger.Errorf("Failed (skip these metrics): %s", err)
+		return nil, err
+	}
+
+	now := time.Now()
+	prevValues, prevTime := g.prevValues, g.prevTime
+	g.prevValues, g.prevTime = currValues, now
+	if prevValues != nil {
+		for k, v := range calcSystemdRates(currValues, prevValues, now.Sub(prevTime)) {
+			ret[k] = v
+		}
+	}
+	return ret, nil
+}
+
+// walkSlice calls fn for each unit included in the slice, descending into
+// the nested slices such as system-getty.slice for templated units.
+func (g *SystemdGenerator) walkSlice(root, slice string, fn func(unit string, cgroup *util.Cgroup)) error {
+	entries, err := os.ReadDir(filepath.Join(root, slice))
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() {
+			continue
+		}
+		path := slice + "/" + name
+		if strings.HasSuffix(name, ".slice") {
+			if err := g.walkSlice(root, path, fn); err != nil {
+				systemdLogger.Warningf("Failed to read %s: %s", path, err)
+			}
+			continue
+		}
+		if g.included(name) {
+			fn(name, &util.Cgroup{Root: root, Path: path})
+		}
+	}
+	return nil
+}
+
+func (g *SystemdGenerator) included(unit string) bool {
+	// the directories of nested slices and cgroup.* files
+	if strings.HasSuffix(unit, ".slice") || !strings.Contains(unit, ".") {
+		return false
+	}
+	if g.IncludeRegexp != nil && !g.IncludeRegexp.MatchString(unit) {
+		return false
+	}
+	if g.ExcludeRegexp != nil && g.ExcludeRegexp.MatchString(unit) {
+		return false
+	}
+	return true
+}
+
+// collectSystemdUnitStats reads the interface files of the cgroup of a unit.
+// The files of disabled controllers are skipped.
+func collectSystemdUnitStats(cgroup *util.Cgroup) map[string]uint64 {
+	stats := make(map[string]uint64)
+	if values, err := cgroup.ReadKeyedValues("cpu.stat"); err == nil {
+		for k, v := range values {
+			if k == "user_usec" || k == "system_usec" {
+				stats[k] = v
+			}
+		}
+	}
+	for _, name := range []string{"memory.current", "memory.peak", "pids.current"} {
+		out, err := cgroup.ReadFile(name)
+		if err != nil {
+			continue
+		}
+		if v, err := strconv.ParseUint(strings.TrimSpace(string(out)), 10, 64); err == nil {
+			stats[name] = v
+		}
+	}
+	if out, err := cgroup.ReadFile("io.stat"); err == nil {
+		rbytes, wbytes := parseIOStat(out)
+		stats["rbytes"], stats["wbytes"] = rbytes, wbytes
+	}
+	return stats
+}
+
+// parseIOStat returns the total bytes read and written on all devices in io.stat.
+func parseIOStat(out []byte) (rbytes, wbytes uint64) {
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		for _, field := range fields[min(1, len(fields)):] {
+			k, v, ok := strings.Cut(field, "=")
+			if !ok {
+				continue
+			}
+			n, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				continue
+			}
+			switch k {
+			case "rbytes":
+				rbytes += n
+			case "wbytes":
+				wbytes += n
+			}
+		}
+	}
+	return rbytes, wbytes
+}
+
+func systemdGauges(name string, stats map[string]uint64) metrics.Values {
+	keys := map[string]string{
+		"memory.current": "custom.systemd." + name + ".memory.current",
+		"memory.peak":    "custom.systemd." + name + ".memory.peak",
+		"pids.current":   "custom.systemd." + name + ".pids.current",
+	}
+	ret := make(metrics.Values)
+	for stat, key := range keys {
+		if v, ok := stats[stat]; ok {
+			ret[key] = float64(v)
+		}
+	}
+	return ret
+}
+
+func calcSystemdRates(currValues, prevValues map[string]map[string]uint64, elapsed time.Duration) metrics.Values {
+	ret := make(metrics.Values)
+	if elapsed <= 0 {
+		return ret
+	}
+	for name, curr := range currValues {
+		prev, ok := prevValues[name]
+		if !ok {
+			continue
+		}
+		diff := func(stat string) (float64, bool) {
+			c, ok1 := curr[stat]
+			p, ok2 := prev[stat]
+			return float64(util.DiffResettableCounter(c, p)), ok1 && ok2
+		}
+		if d, ok := diff("user_usec"); ok {
+			ret["custom.systemd."+name+".cpu.user"] = d * 100 / float64(elapsed.Microseconds())
+		}
+		if d, ok := diff("system_usec"); ok {
+			ret["custom.systemd."+name+".cpu.system"] = d * 100 / float64(elapsed.Microseconds())
+		}
+		if d, ok := diff("rbytes"); ok {
+			ret["custom.systemd."+name+".io.read"] = d / elapsed.Seconds()
+		}
+		if d, ok := diff("wbytes"); ok {
+			ret["custom.systemd."+name+".io.write"] = d / elapsed.Seconds()
+		}
+	}
+	return ret
+}
+
+// CustomIdentifier for PluginGenerator interface
+func (g *SystemdGenerator) CustomIdentifier() *string {
+	return nil
+}
+
+// PrepareGraphDefs for PluginGenerator interface
+func (g *SystemdGenerator) PrepareGraphDefs() ([]*mkr.GraphDefsParam, error) {
+	return metrics.NewGraphDefsParam(map[string]metrics.CustomGraphDef{
+		"systemd.#.cpu": {
+			Label: "Systemd Unit CPU %1",
+			Unit:  "percentage",
+			Metrics: []metrics.CustomGraphMetricDef{
+				{Name: "user", Label: "User", Stacked: true},
+				{Name: "system", Label: "System", Stacked: true},
+			},
+		},
+		"systemd.#.memory": {
+			Label: "Systemd Unit Memory %1",
+			Unit:  "bytes",
+			Metrics: []metrics.CustomGraphMetricDef{
+				{Name: "current", Label: "Current"},
+				{Name: "peak", Label: "Peak"},
+			},
+		},
+		"systemd.#.io": {
+			Label: "Systemd Unit I/O %1",
+			Unit:  "bytes/sec",
+			Metrics: []metrics.CustomGraphMetricDef{
+				{Name: "read", Label: "Read"},
+				{Name: "write", Label: "Write"},
+			},
+		},
+		"systemd.#.pids": {
+			Label: "Systemd Unit Tasks %1",
+			Unit:  "integer",
+			Metrics: []metrics.CustomGraphMetricDef{
+				{Name: "current", Label: "Current"},
+			},
+		},
+	}), nil
+}
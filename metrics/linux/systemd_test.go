@@ -0,0 +1,125 @@
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/mackerelio/mackerel-agent/metrics"
+)
+
+func TestSystemdGenerator(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"system.slice/nginx.service/cpu.stat":                         "usage_usec 1000\nuser_usec 600\nsystem_usec 400\n",
+		"system.slice/nginx.service/memory.current":                   "10485760\n",
+		"system.slice/nginx.service/memory.peak":                      "20971520\n",
+		"system.slice/nginx.service/pids.current":                     "5\n",
+		"system.slice/nginx.service/io.stat":                          "8:0 rbytes=4096 wbytes=8192 rios=1 wios=2 dbytes=0 dios=0\n",
+		"system.slice/systemd-journald.service/cpu.stat":              "usage_usec 10\nuser_usec 5\nsystem_usec 5\n",
+		"system.slice/system-getty.slice/getty@tty1.service/cpu.stat": "usage_usec 10\nuser_usec 5\nsystem_usec 5\n",
+		"system.slice/cgroup.procs":                                   "",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	g := &SystemdGenerator{ExcludeRegexp: regexp.MustCompile(`^systemd-`), Root: root}
+	values, err := g.Generate()
+	if err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+	expect := metrics.Values{
+		"custom.systemd.nginx.memory.current": 10485760,
+		"custom.systemd.nginx.memory.peak":    20971520,
+		"custom.systemd.nginx.pids.current":   5,
+	}
+	if !reflect.DeepEqual(values, expect) {
+		t.Errorf("values should be %+v but %+v", expect, values)
+	}
+
+	values, err = g.Generate()
+	if err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+	for _, name := range []string{"custom.systemd.nginx.cpu.user", "custom.systemd.nginx.io.read", "custom.systemd.getty_tty1.cpu.user"} {
+		if v, ok := values[name]; !ok || v != 0 {
+			t.Errorf("%s should be 0: %+v", name, values)
+		}
+	}
+
+	// The units in nested slices are matched by the unit names.
+	g = &SystemdGenerator{IncludeRegexp: regexp.MustCompile(`^getty@`), Root: root}
+	g.Generate()
+	values, err = g.Generate()
+	if err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+	expect = metrics.Values{
+		"custom.systemd.getty_tty1.cpu.user":   0,
+		"custom.systemd.getty_tty1.cpu.system": 0,
+	}
+	if !reflect.DeepEqual(values, expect) {
+		t.Errorf("values should be %+v but %+v", expect, values)
+	}
+}
+
+func TestSystemdGenerator_Included(t *testing.T) {
+	g := &SystemdGenerator{IncludeRegexp: regexp.MustCompile(`^(nginx|mysql)\.service$`)}
+	tests := map[string]bool{
+		"nginx.service":      true,
+		"mysql.service":      true,
+		"sshd.service":       false,
+		"getty@tty1.service": false,
+		"system-getty.slice": false,
+		"cgroup.procs":       false,
+	}
+	for unit, expect := range tests {
+		if got := g.included(unit); got != expect {
+			t.Errorf("included(%q) should be %v", unit, expect)
+		}
+	}
+}
+
+func TestCalcSystemdRates(t *testing.T) {
+	prev := map[string]map[string]uint64{
+		"nginx":   {"user_usec": 1000000, "system_usec": 500000, "rbytes": 0, "wbytes": 1024},
+		"removed": {"user_usec": 1},
+	}
+	curr := map[string]map[string]uint64{
+		"nginx": {"user_usec": 31000000, "system_usec": 6500000, "rbytes": 6144000, "wbytes": 1024},
+		"added": {"user_usec": 1},
+	}
+
+	values := calcSystemdRates(curr, prev, 60*time.Second)
+	expect := metrics.Values{
+		"custom.systemd.nginx.cpu.user":   50,
+		"custom.systemd.nginx.cpu.system": 10,
+		"custom.systemd.nginx.io.read":    102400,
+		"custom.systemd.nginx.io.write":   0,
+	}
+	if !reflect.DeepEqual(values, expect) {
+		t.Errorf("values should be %+v but %+v", expect, values)
+	}
+}
+
+func TestParseIOStat(t *testing.T) {
+	rbytes, wbytes := parseIOStat([]byte(`8:0 rbytes=90430464 wbytes=299008000 rios=8950 wios=12252 dbytes=0 dios=0
+8:16 rbytes=1024 wbytes=2048 rios=1 wios=2 dbytes=0 dios=0
+`))
+	if rbytes != 90431488 || wbytes != 299010048 {
+		t.Errorf("unexpected bytes: %d, %d", rbytes, wbytes)
+	}
+}
@@ -0,0 +1,303 @@
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mackerelio/golib/logging"
+	"github.com/mackerelio/mackerel-agent/metrics"
+	"github.com/mackerelio/mackerel-agent/util"
+	mkr "github.com/mackerelio/mackerel-client-go"
+)
+
+/*
+collect TCP connections and socket statistics
+
+`custom.tcp.states.{state}`: The number of TCP connections in the state retrieved from /proc/net/tcp and /proc/net/tcp6
+
+state = "established", "syn_sent", "syn_recv", "fin_wait1", "fin_wait2", "time_wait", "close", "close_wait", "last_ack", "listen", "closing"
+
+`custom.sockstat.{metric}`: The number of sockets retrieved from /proc/net/sockstat (tcp and udp are of IPv4)
+
+metric = "sockets_used", "tcp_inuse", "tcp_orphan", "tcp_tw", "tcp_alloc", "udp_inuse"
+
+`custom.tcp.listen.{overflows,drops}`: The listen queue overflows/drops per second retrieved from /proc/net/netstat (TcpExt)
+
+`custom.tcp.segments.{in,out,retransmitted}`: The TCP segments per second retrieved from /proc/net/snmp (Tcp)
+
+`custom.tcp.connections.{active_opens,passive_opens,attempt_fails,estab_resets}`: The TCP connection events per second retrieved from /proc/net/snmp (Tcp)
+
+cat /proc/net/tcp sample:
+	  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+	   0: 0100007F:0CEA 00000000:0000 0A 00000000:00000000 00:00000000 00000000   999        0 20460 1 0000000000000000 100 0 0 10 0
+
+cat /proc/net/sockstat sample:
+	sockets: used 236
+	TCP: inuse 5 orphan 0 tw 2 alloc 7 mem 1
+	UDP: inuse 2 mem 0
+
+cat /proc/net/snmp sample (/proc/net/netstat is in the same format):
+	Tcp: RtoAlgorithm RtoMin RtoMax MaxConn ActiveOpens PassiveOpens AttemptFails EstabResets CurrEstab InSegs OutSegs RetransSegs InErrs OutRsts InCsumErrors
+	Tcp: 1 200 120000 -1 1543 12 2 10 3 112849 109911 37 0 220 0
+*/
+
+// TCPGenerator generates TCP connections and socket statistics
+type TCPGenerator struct {
+	mu         sync.Mutex
+	prevValues map[string]uint64
+	prevTime   time.Time
+}
+
+var tcpLogger = logging.GetLogger("metrics.tcp")
+
+// the states in include/net/tcp_states.h
+var tcpStates = map[string]string{
+	"01": "established",
+	"02": "syn_sent",
+	"03": "syn_recv",
+	"04": "fin_wait1",
+	"05": "fin_wait2",
+	"06": "time_wait",
+	"07": "close",
+	"08": "close_wait",
+	"09": "last_ack",
+	"0A": "listen",
+	"0B": "closing",
+}
+
+// the values in /proc/net/sockstat
+var sockstatMetricKeys = map[string]string{
+	"sockets.used": "custom.sockstat.sockets_used",
+	"TCP.inuse":    "custom.sockstat.tcp_inuse",
+	"TCP.orphan":   "custom.sockstat.tcp_orphan",
+	"TCP.tw":       "custom.sockstat.tcp_tw",
+	"TCP.alloc":    "custom.sockstat.tcp_alloc",
+	"UDP.inuse":    "custom.sockstat.udp_inuse",
+}
+
+// the counters in /proc/net/netstat and /proc/net/snmp
+var tcpRates = map[string]string{
+	"TcpExt.ListenOverflows": "custom.tcp.listen.overflows",
+	"TcpExt.ListenDrops":     "custom.tcp.listen.drops",
+	"Tcp.InSegs":             "custom.tcp.segments.in",
+	"Tcp.OutSegs":            "custom.tcp.segments.out",
+	"Tcp.RetransSegs":        "custom.tcp.segments.retransmitted",
+	"Tcp.ActiveOpens":        "custom.tcp.connections.active_opens",
+	"Tcp.PassiveOpens":       "custom.tcp.connections.passive_opens",
+	"Tcp.AttemptFails":       "custom.tcp.connections.attempt_fails",
+	"Tcp.EstabResets":        "custom.tcp.connections.estab_resets",
+}
+
+// Generate TCP connections and socket statistics
+func (g *TCPGenerator) Generate() (metrics.Values, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ret := make(metrics.Values)
+	counts := make(map[string]uint64)
+	for _, path := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		if err := countTCPStatesFile(path, counts); err != nil {
+			// /proc/net/tcp6 does not exist if IPv6 is disabled.
+			if os.IsNotExist(err) {
+				continue
+			}
+			tcpLogger.Warningf("Failed to read %s: %s", path, err)
+		}
+	}
+	for _, state := range tcpStates {
+		ret["custom.tcp.states."+state] = float64(counts[state])
+	}
+
+	if out, err := os.ReadFile("/proc/net/sockstat"); err != nil {
+		tcpLogger.Warningf("Failed to read sockstat: %s", err)
+	} else {
+		for k, v := range parseSockstat(out) {
+			ret[k] = v
+		}
+	}
+
+	currValues := make(map[string]uint64)
+	for _, path := range []string{"/proc/net/netstat", "/proc/net/snmp"} {
+		out, err := os.ReadFile(path)
+		if err != nil {
+			tcpLogger.Warningf("Failed to read %s: %s", path, err)
+			continue
+		}
+		for k, v := range parseNetSNMP(out) {
+			currValues[k] = v
+		}
+	}
+	now := time.Now()
+	prevValues, prevTime := g.prevValues, g.prevTime
+	g.prevValues, g.prevTime = currValues, now
+	if prevValues != nil {
+		for k, v := range calcTCPRates(currValues, prevValues, now.Sub(prevTime)) {
+			ret[k] = v
+		}
+	}
+	return ret, nil
+}
+
+func countTCPStatesFile(path string, counts map[string]uint64) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return countTCPStates(f, counts)
+}
+
+// countTCPStates counts the connections of each state in /proc/net/tcp{,6}.
+func countTCPStates(r io.Reader, counts map[string]uint64) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Scan() // skip the header
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 {
+			continue
+		}
+		if state, ok := tcpStates[fields[3]]; ok {
+			counts[state]++
+		}
+	}
+	return scanner.Err()
+}
+
+func parseSockstat(out []byte) metrics.Values {
+	ret := make(metrics.Values)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		proto, rest, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(rest)
+		for i := 0; i+1 < len(fields); i += 2 {
+			key, ok := sockstatMetricKeys[proto+"."+fields[i]]
+			if !ok {
+				continue
+			}
+			v, err := strconv.ParseFloat(fields[i+1], 64)
+			if err != nil {
+				continue
+			}
+			ret[key] = v
+		}
+	}
+	return ret
+}
+
+// parseNetSNMP parses the pairs of header and value lines in /proc/net/snmp
+// and /proc/net/netstat, and returns the values keyed by "Prefix.Name".
+func parseNetSNMP(out []byte) map[string]uint64 {
+	values := make(map[string]uint64)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		header := strings.Fields(scanner.Text())
+		if !scanner.Scan() {
+			break
+		}
+		fields := strings.Fields(scanner.Text())
+		if len(header) != len(fields) || len(header) == 0 || header[0] != fields[0] {
+			tcpLogger.Warningf("Failed to parse the values of %s", strings.Join(header, " "))
+			continue
+		}
+		prefix := strings.TrimSuffix(header[0], ":")
+		for i := 1; i < len(header); i++ {
+			// some values such as Tcp.MaxConn may be negative
+			v, err := strconv.ParseUint(fields[i], 10, 64)
+			if err != nil {
+				continue
+			}
+			values[prefix+"."+header[i]] = v
+		}
+	}
+	return values
+}
+
+func calcTCPRates(currValues, prevValues map[string]uint64, elapsed time.Duration) metrics.Values {
+	ret := make(metrics.Values)
+	if elapsed <= 0 {
+		return ret
+	}
+	for name, key := range tcpRates {
+		curr, ok := currValues[name]
+		if !ok {
+			continue
+		}
+		if prev, ok := prevValues[name]; ok {
+			ret[key] = float64(util.DiffResettableCounter(curr, prev)) / elapsed.Seconds()
+		}
+	}
+	return ret
+}
+
+// CustomIdentifier for PluginGenerator interface
+func (g *TCPGenerator) CustomIdentifier() *string {
+	return nil
+}
+
+// PrepareGraphDefs for PluginGenerator interface
+func (g *TCPGenerator) PrepareGraphDefs() ([]*mkr.GraphDefsParam, error) {
+	stateMetrics := make([]metrics.CustomGraphMetricDef, 0, len(tcpStates))
+	for i := 1; i <= len(tcpStates); i++ {
+		state := tcpStates[fmt.Sprintf("%02X", i)]
+		stateMetrics = append(stateMetrics, metrics.CustomGraphMetricDef{Name: state, Label: strings.ToUpper(state), Stacked: true})
+	}
+	return metrics.NewGraphDefsParam(map[string]metrics.CustomGraphDef{
+		"tcp.states": {
+			Label:   "TCP Connection States",
+			Unit:    "integer",
+			Metrics: stateMetrics,
+		},
+		"sockstat": {
+			Label: "Sockets",
+			Unit:  "integer",
+			Metrics: []metrics.CustomGraphMetricDef{
+				{Name: "sockets_used", Label: "Used"},
+				{Name: "tcp_inuse", Label: "TCP In Use"},
+				{Name: "tcp_orphan", Label: "TCP Orphan"},
+				{Name: "tcp_tw", Label: "TCP TIME_WAIT"},
+				{Name: "tcp_alloc", Label: "TCP Allocated"},
+				{Name: "udp_inuse", Label: "UDP In Use"},
+			},
+		},
+		"tcp.listen": {
+			Label: "TCP Listen Queue",
+			Unit:  "float",
+			Metrics: []metrics.CustomGraphMetricDef{
+				{Name: "overflows", Label: "Overflows"},
+				{Name: "drops", Label: "Drops"},
+			},
+		},
+		"tcp.segments": {
+			Label: "TCP Segments",
+			Unit:  "float",
+			Metrics: []metrics.CustomGraphMetricDef{
+				{Name: "in", Label: "In"},
+				{Name: "out", Label: "Out"},
+				{Name: "retransmitted", Label: "Retransmitted"},
+			},
+		},
+		"tcp.connections": {
+			Label: "TCP Connection Events",
+			Unit:  "float",
+			Metrics: []metrics.CustomGraphMetricDef{
+				{Name: "active_opens", Label: "Active Opens"},
+				{Name: "passive_opens", Label: "Passive Opens"},
+				{Name: "attempt_fails", Label: "Attempt Fails"},
+				{Name: "estab_resets", Label: "Established Resets"},
+			},
+		},
+	}), nil
+}
@@ -0,0 +1,111 @@
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mackerelio/mackerel-agent/metrics"
+)
+
+func TestTCPGenerator(t *testing.T) {
+	g := &TCPGenerator{}
+	if _, err := g.Generate(); err != nil {
+		t.Errorf("should not raise error: %v", err)
+	}
+
+	time.Sleep(1 * time.Second)
+	values, err := g.Generate()
+	if err != nil {
+		t.Errorf("should not raise error: %v", err)
+	}
+	for _, name := range []string{
+		"custom.tcp.states.established",
+		"custom.tcp.states.listen",
+		"custom.sockstat.sockets_used",
+		"custom.tcp.listen.overflows",
+		"custom.tcp.segments.retransmitted",
+	} {
+		if _, ok := values[name]; !ok {
+			t.Errorf("tcp metrics should have %s: %+v", name, values)
+		}
+	}
+}
+
+func TestCountTCPStates(t *testing.T) {
+	tcp := `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 0100007F:0CEA 00000000:0000 0A 00000000:00000000 00:00000000 00000000   999        0 20460 1 0000000000000000 100 0 0 10 0
+   1: 0F02000A:0016 0202000A:C5D4 01 00000000:00000000 02:000A7B4D 00000000     0        0 24013 2 0000000000000000 20 4 31 10 -1
+   2: 0F02000A:0016 0202000A:C5D6 06 00000000:00000000 03:00000F5A 00000000     0        0 0 3 0000000000000000
+`
+	tcp6 := `  sl  local_address                         remote_address                        st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000000000000000000000000000:0016 00000000000000000000000000000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 17857 1 0000000000000000 100 0 0 10 0
+`
+	counts := make(map[string]uint64)
+	for _, s := range []string{tcp, tcp6} {
+		if err := countTCPStates(strings.NewReader(s), counts); err != nil {
+			t.Errorf("should not raise error: %v", err)
+		}
+	}
+	expect := map[string]uint64{"listen": 2, "established": 1, "time_wait": 1}
+	if !reflect.DeepEqual(counts, expect) {
+		t.Errorf("counts should be %+v but %+v", expect, counts)
+	}
+}
+
+func TestParseSockstat(t *testing.T) {
+	values := parseSockstat([]byte(`sockets: used 236
+TCP: inuse 5 orphan 0 tw 2 alloc 7 mem 1
+UDP: inuse 2 mem 0
+UDPLITE: inuse 0
+RAW: inuse 0
+FRAG: inuse 0 memory 0
+`))
+	expect := metrics.Values{
+		"custom.sockstat.sockets_used": 236,
+		"custom.sockstat.tcp_inuse":    5,
+		"custom.sockstat.tcp_orphan":   0,
+		"custom.sockstat.tcp_tw":       2,
+		"custom.sockstat.tcp_alloc":    7,
+		"custom.sockstat.udp_inuse":    2,
+	}
+	if !reflect.DeepEqual(values, expect) {
+		t.Errorf("values should be %+v but %+v", expect, values)
+	}
+}
+
+func TestCalcTCPRates(t *testing.T) {
+	prev := parseNetSNMP([]byte(`Tcp: RtoAlgorithm RtoMin RtoMax MaxConn ActiveOpens PassiveOpens AttemptFails EstabResets CurrEstab InSegs OutSegs RetransSegs InErrs OutRsts InCsumErrors
+Tcp: 1 200 120000 -1 1543 12 2 10 3 112849 109911 37 0 220 0
+TcpExt: SyncookiesSent ListenOverflows ListenDrops
+TcpExt: 0 5 5
+`))
+	curr := parseNetSNMP([]byte(`Tcp: RtoAlgorithm RtoMin RtoMax MaxConn ActiveOpens PassiveOpens AttemptFails EstabResets CurrEstab InSegs OutSegs RetransSegs InErrs OutRsts InCsumErrors
+Tcp: 1 200 120000 -1 1603 132 2 70 3 118849 115911 97 0 220 0
+TcpExt: SyncookiesSent ListenOverflows ListenDrops
+TcpExt: 0 125 245
+`))
+	if _, ok := curr["Tcp.MaxConn"]; ok {
+		t.Error("negative values should be skipped")
+	}
+
+	values := calcTCPRates(curr, prev, 60*time.Second)
+	expect := metrics.Values{
+		"custom.tcp.listen.overflows":          2,
+		"custom.tcp.listen.drops":              4,
+		"custom.tcp.segments.in":               100,
+		"custom.tcp.segments.out":              100,
+		"custom.tcp.segments.retransmitted":    1,
+		"custom.tcp.connections.active_opens":  1,
+		"custom.tcp.connections.passive_opens": 2,
+		"custom.tcp.connections.attempt_fails": 0,
+		"custom.tcp.connections.estab_resets":  1,
+	}
+	if !reflect.DeepEqual(values, expect) {
+		t.Errorf("values should be %+v but %+v", expect, values)
+	}
+}
@@ -0,0 +1,239 @@
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mackerelio/golib/logging"
+	"github.com/mackerelio/mackerel-agent/metrics"
+	"github.com/mackerelio/mackerel-agent/util"
+	mkr "github.com/mackerelio/mackerel-client-go"
+)
+
+/*
+collect virtual memory statistics
+
+`custom.vmstat.swap.{in,out}`: The pages swapped in/out per second retrieved from /proc/vmstat (pswpin, pswpout)
+
+`custom.vmstat.faults.major`: The major page faults per second (pgmajfault)
+
+`custom.vmstat.reclaim.{scan,steal}`: The pages scanned/reclaimed per second by kswapd, direct reclaim and khugepaged (pgscan_*, pgsteal_*)
+
+`custom.vmstat.oom_kill.count`: The number of processes killed by the OOM killer since the previous collection (oom_kill, Linux 4.13+)
+
+`custom.meminfo.{metric}`: The memory size in bytes retrieved from /proc/meminfo
+
+metric = "dirty", "writeback", "slab", "committed_as"
+
+`custom.meminfo.hugepages.{total,free}`: The number of huge pages
+
+The generator keeps the values of the previous collection, so it generates no rates at the first collection.
+*/
+
+// VmstatGenerator generates virtual memory statistics
+type VmstatGenerator struct {
+	mu         sync.Mutex
+	prevValues map[string]uint64
+	prevTime   time.Time
+}
+
+var vmstatLogger = logging.GetLogger("metrics.vmstat")
+
+// /proc/meminfo keys in kB
+var meminfoMetricKeys = map[string]string{
+	"Dirty":        "custom.meminfo.dirty",
+	"Writeback":    "custom.meminfo.writeback",
+	"Slab":         "custom.meminfo.slab",
+	"Committed_AS": "custom.meminfo.committed_as",
+}
+
+// /proc/meminfo keys in pages
+var meminfoHugepagesKeys = map[string]string{
+	"HugePages_Total": "custom.meminfo.hugepages.total",
+	"HugePages_Free":  "custom.meminfo.hugepages.free",
+}
+
+// Generate virtual memory statistics
+func (g *VmstatGenerator) Generate() (metrics.Values, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	vmstat, err := os.ReadFile("/proc/vmstat")
+	if err != nil {
+		vmstatLogger.Errorf("Failed (skip these metrics): %s", err)
+		return nil, err
+	}
+	meminfo, err := os.ReadFile("/proc/meminfo")
+	if err != nil {
+		vmstatLogger.Errorf("Failed (skip these metrics): %s", err)
+		return nil, err
+	}
+
+	currValues := parseVmstat(vmstat)
+	now := time.Now()
+	prevValues, prevTime := g.prevValues, g.prevTime
+	g.prevValues, g.prevTime = currValues, now
+
+	ret := parseMeminfoExtras(meminfo)
+	if prevValues != nil {
+		for k, v := range calcVmstat(currValues, prevValues, now.Sub(prevTime)) {
+			ret[k] = v
+		}
+	}
+	return ret, nil
+}
+
+// parseVmstat parses lines like "pswpin 12345" in /proc/vmstat.
+func parseVmstat(out []byte) map[string]uint64 {
+	values := make(map[string]uint64)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		v, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		values[fields[0]] = v
+	}
+	return values
+}
+
+// vmstatRates maps metric names to the counters in /proc/vmstat. The counters
+// are matched by prefix to sum up per-zone counters on older kernels
+// (e.g. pgscan_kswapd_normal).
+var vmstatRates = map[string][]string{
+	"custom.vmstat.swap.in":       {"pswpin"},
+	"custom.vmstat.swap.out":      {"pswpout"},
+	"custom.vmstat.faults.major":  {"pgmajfault"},
+	"custom.vmstat.reclaim.scan":  {"pgscan_kswapd", "pgscan_direct", "pgscan_khugepaged"},
+	"custom.vmstat.reclaim.steal": {"pgsteal_kswapd", "pgsteal_direct", "pgsteal_khugepaged"},
+}
+
+func calcVmstat(currValues, prevValues map[string]uint64, elapsed time.Duration) metrics.Values {
+	delta := func(prefixes ...string) (float64, bool) {
+		var sum uint64
+		found := false
+		for name, curr := range currValues {
+			// pgscan_direct_throttle counts throttling events, not pages.
+			if name == "pgscan_direct_throttle" {
+				continue
+			}
+			for _, prefix := range prefixes {
+				if !strings.HasPrefix(name, prefix) {
+					continue
+				}
+				if prev, ok := prevValues[name]; ok {
+					sum += util.DiffResettableCounter(curr, prev)
+					found = true
+				}
+			}
+		}
+		return float64(sum), found
+	}
+
+	ret := make(metrics.Values)
+	for key, names := range vmstatRates {
+		if d, ok := delta(names...); ok {
+			ret[key] = d / elapsed.Seconds()
+		}
+	}
+	if d, ok := delta("oom_kill"); ok {
+		ret["custom.vmstat.oom_kill.count"] = d
+	}
+	return ret
+}
+
+func parseMeminfoExtras(out []byte) metrics.Values {
+	ret := make(metrics.Values)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		k, v, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(v)
+		if len(fields) == 0 {
+			continue
+		}
+		value, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			continue
+		}
+		if key, ok := meminfoMetricKeys[k]; ok {
+			ret[key] = value * 1024
+		} else if key, ok := meminfoHugepagesKeys[k]; ok {
+			ret[key] = value
+		}
+	}
+	return ret
+}
+
+// CustomIdentifier for PluginGenerator interface
+func (g *VmstatGenerator) CustomIdentifier() *string {
+	return nil
+}
+
+// PrepareGraphDefs for PluginGenerator interface
+func (g *VmstatGenerator) PrepareGraphDefs() ([]*mkr.GraphDefsParam, error) {
+	return metrics.NewGraphDefsParam(map[string]metrics.CustomGraphDef{
+		"vmstat.swap": {
+			Label: "Swap Pages",
+			Unit:  "float",
+			Metrics: []metrics.CustomGraphMetricDef{
+				{Name: "in", Label: "In"},
+				{Name: "out", Label: "Out"},
+			},
+		},
+		"vmstat.faults": {
+			Label: "Page Faults",
+			Unit:  "float",
+			Metrics: []metrics.CustomGraphMetricDef{
+				{Name: "major", Label: "Major"},
+			},
+		},
+		"vmstat.reclaim": {
+			Label: "Page Reclaim",
+			Unit:  "float",
+			Metrics: []metrics.CustomGraphMetricDef{
+				{Name: "scan", Label: "Scanned"},
+				{Name: "steal", Label: "Reclaimed"},
+			},
+		},
+		"vmstat.oom_kill": {
+			Label: "OOM Kills",
+			Unit:  "integer",
+			Metrics: []metrics.CustomGraphMetricDef{
+				{Name: "count", Label: "Count"},
+			},
+		},
+		"meminfo": {
+			Label: "Memory Details",
+			Unit:  "bytes",
+			Metrics: []metrics.CustomGraphMetricDef{
+				{Name: "dirty", Label: "Dirty"},
+				{Name: "writeback", Label: "Writeback"},
+				{Name: "slab", Label: "Slab"},
+				{Name: "committed_as", Label: "Committed AS"},
+			},
+		},
+		"meminfo.hugepages": {
+			Label: "Huge Pages",
+			Unit:  "integer",
+			Metrics: []metrics.CustomGraphMetricDef{
+				{Name: "total", Label: "Total"},
+				{Name: "free", Label: "Free"},
+			},
+		},
+	}), nil
+}
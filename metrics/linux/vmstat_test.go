@@ -0,0 +1,101 @@
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mackerelio/mackerel-agent/metrics"
+)
+
+func TestVmstatGenerator(t *testing.T) {
+	g := &VmstatGenerator{}
+	values, err := g.Generate()
+	if err != nil {
+		t.Errorf("should not raise error: %v", err)
+	}
+	if _, ok := values["custom.meminfo.dirty"]; !ok {
+		t.Errorf("meminfo should be generated at the first collection: %+v", values)
+	}
+	if _, ok := values["custom.vmstat.swap.in"]; ok {
+		t.Errorf("rates should not be generated at the first collection: %+v", values)
+	}
+
+	time.Sleep(1 * time.Second)
+	values, err = g.Generate()
+	if err != nil {
+		t.Errorf("should not raise error: %v", err)
+	}
+	for _, name := range []string{"custom.vmstat.swap.in", "custom.vmstat.swap.out", "custom.vmstat.faults.major"} {
+		if _, ok := values[name]; !ok {
+			t.Errorf("vmstat should have %s: %+v", name, values)
+		}
+	}
+	t.Logf("vmstat metrics: %+v", values)
+}
+
+func TestCalcVmstat(t *testing.T) {
+	prev := parseVmstat([]byte(`pswpin 100
+pswpout 200
+pgmajfault 1000
+pgscan_kswapd 5000
+pgscan_direct 1000
+pgscan_direct_throttle 0
+pgscan_anon 6000
+pgsteal_kswapd 4000
+pgsteal_direct 800
+pgsteal_anon 4800
+oom_kill 1
+`))
+	curr := parseVmstat([]byte(`pswpin 160
+pswpout 320
+pgmajfault 1600
+pgscan_kswapd 8000
+pgscan_direct 4000
+pgscan_direct_throttle 60
+pgscan_anon 12000
+pgsteal_kswapd 5200
+pgsteal_direct 2000
+pgsteal_anon 7200
+oom_kill 3
+`))
+
+	values := calcVmstat(curr, prev, 60*time.Second)
+	expect := metrics.Values{
+		"custom.vmstat.swap.in":        1,
+		"custom.vmstat.swap.out":       2,
+		"custom.vmstat.faults.major":   10,
+		"custom.vmstat.reclaim.scan":   100,
+		"custom.vmstat.reclaim.steal":  40,
+		"custom.vmstat.oom_kill.count": 2,
+	}
+	if !reflect.DeepEqual(values, expect) {
+		t.Errorf("values should be %+v but %+v", expect, values)
+	}
+}
+
+func TestParseMeminfoExtras(t *testing.T) {
+	values := parseMeminfoExtras([]byte(`MemTotal:        8167848 kB
+Dirty:               120 kB
+Writeback:             0 kB
+Slab:             262144 kB
+Committed_AS:    4194304 kB
+HugePages_Total:      16
+HugePages_Free:        8
+Hugepagesize:       2048 kB
+`))
+	expect := metrics.Values{
+		"custom.meminfo.dirty":           120 * 1024,
+		"custom.meminfo.writeback":       0,
+		"custom.meminfo.slab":            262144 * 1024,
+		"custom.meminfo.committed_as":    4194304 * 1024,
+		"custom.meminfo.hugepages.total": 16,
+		"custom.meminfo.hugepages.free":  8,
+	}
+	if !reflect.DeepEqual(values, expect) {
+		t.Errorf("values should be %+v but %+v", expect, values)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/mackerelio/golib/logging"
 	"github.com/mackerelio/mackerel-agent/config"
@@ -16,22 +17,26 @@ import (
 // pluginGenerator collects user-defined metrics.
 // mackerel-agent runs specified command and parses the result for the metric names and values.
 type pluginGenerator struct {
+	Name   string
 	Config *config.MetricPlugin
 	Meta   *pluginMeta
+	Stats  *AgentStats
 }
 
 // pluginMeta is generated from plugin command. (not the configuration file)
 type pluginMeta struct {
-	Graphs map[string]customGraphDef
+	Graphs map[string]CustomGraphDef
 }
 
-type customGraphDef struct {
+// CustomGraphDef is a graph definition of custom metrics.
+type CustomGraphDef struct {
 	Label   string
 	Unit    string
-	Metrics []customGraphMetricDef
+	Metrics []CustomGraphMetricDef
 }
 
-type customGraphMetricDef struct {
+// CustomGraphMetricDef is a metric definition in CustomGraphDef.
+type CustomGraphMetricDef struct {
 	Name    string
 	Label   string
 	Stacked bool
@@ -44,8 +49,20 @@ const pluginPrefix = "custom."
 var pluginConfigurationEnvName = "MACKEREL_AGENT_PLUGIN_META"
 
 // NewPluginGenerator XXX
-func NewPluginGenerator(conf *config.MetricPlugin) PluginGenerator {
-	return &pluginGenerator{Config: conf}
+// The executions of the plugin are recorded in stats as the name.
+func NewPluginGenerator(name string, conf *config.MetricPlugin, stats *AgentStats) PluginGenerator {
+	return &pluginGenerator{Name: name, Config: conf, Stats: stats}
+}
+
+// IsCommandPlugin reports whether g runs a plugin command, that is, it is
+// created by NewPluginGenerator.
+func IsCommandPlugin(g PluginGenerator) bool {
+	_, ok := g.(*pluginGenerator)
+	return ok
+}
+
+func (g *pluginGenerator) String() string {
+	return g.Config.Command.CommandString()
 }
 
 func (g *pluginGenerator) Generate() (Values, error) {
@@ -182,6 +199,13 @@ func (g *pluginGenerator) makeGraphDefsParam() []*mkr.GraphDefsParam {
 	return makeGraphDefsParam(g.Meta)
 }
 
+// NewGraphDefsParam builds graph definitions of custom metrics
+// which are generated by the agent itself.
+// Keys of graphs are graph names without the "custom." prefix.
+func NewGraphDefsParam(graphs map[string]CustomGraphDef) []*mkr.GraphDefsParam {
+	return makeGraphDefsParam(&pluginMeta{Graphs: graphs})
+}
+
 func makeGraphDefsParam(meta *pluginMeta) []*mkr.GraphDefsParam {
 	if meta == nil {
 		return nil
@@ -215,7 +239,9 @@ func makeGraphDefsParam(meta *pluginMeta) []*mkr.GraphDefsParam {
 
 func (g *pluginGenerator) collectValues() (Values, error) {
 	pluginMetaEnv := pluginConfigurationEnvName + "="
-	stdout, stderr, _, err := g.Config.Command.RunWithEnv([]string{pluginMetaEnv})
+	startedAt := time.Now()
+	stdout, stderr, exitCode, err := g.Config.Command.RunWithEnv([]string{pluginMetaEnv})
+	g.Stats.RecordPluginExecution(g.Name, time.Since(startedAt), exitCode, err)
 
 	if stderr != "" {
 		pluginLogger.Infof("command %s outputted to STDERR: %q", g.Config.Command.CommandString(), stderr)
@@ -102,11 +102,11 @@ func TestPluginMakeGraphDefsParam(t *testing.T) {
 	// this plugin emits "one.foo1", "one.foo2" and "two.bar1" metrics
 	g := &pluginGenerator{
 		Meta: &pluginMeta{
-			Graphs: map[string]customGraphDef{
+			Graphs: map[string]CustomGraphDef{
 				"one": {
 					Label: "My Graph One",
 					Unit:  "integer",
-					Metrics: []customGraphMetricDef{
+					Metrics: []CustomGraphMetricDef{
 						{
 							Name:    "foo1",
 							Label:   "Foo(1)",
@@ -121,7 +121,7 @@ func TestPluginMakeGraphDefsParam(t *testing.T) {
 				},
 				"two": {
 					Label: "My Graph Two",
-					Metrics: []customGraphMetricDef{
+					Metrics: []CustomGraphMetricDef{
 						{
 							Name:  "bar1",
 							Label: "Bar(1)",
@@ -0,0 +1,172 @@
+package metrics
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mackerelio/golib/logging"
+	mkr "github.com/mackerelio/mackerel-client-go"
+)
+
+/*
+sample metric values of a generator more often than posting
+
+`{metric}`: The average of the samples collected since the previous collection
+
+`custom.{metric}.{aggregation}`: The other aggregations of the samples, which are
+posted as custom metrics because they are not system metrics known to Mackerel
+
+aggregation = "max", "min", "p95"
+*/
+
+// SamplingGenerator samples values of Generator every Interval and
+// generates their aggregations.
+type SamplingGenerator struct {
+	Family       string // the family of metrics Generator generates, e.g. "cpu"
+	Generator    Generator
+	Interval     time.Duration
+	Aggregations []string
+
+	mu      sync.Mutex
+	samples map[string][]float64
+}
+
+var samplingLogger = logging.GetLogger("metrics.sampling")
+
+// Run samples values until ctx is done.
+func (g *SamplingGenerator) Run(ctx context.Context) {
+	t := time.NewTicker(g.Interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			values, err := g.Generator.Generate()
+			if err != nil {
+				samplingLogger.Warningf("Failed to sample values of %T: %s", g.Generator, err)
+				continue
+			}
+			g.add(values)
+		}
+	}
+}
+
+func (g *SamplingGenerator) add(values Values) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.samples == nil {
+		g.samples = make(map[string][]float64)
+	}
+	for name, value := range values {
+		g.samples[name] = append(g.samples[name], value)
+	}
+}
+
+// Generate aggregates the samples and discards them.
+// When no samples have been collected, it samples values immediately.
+func (g *SamplingGenerator) Generate() (Values, error) {
+	g.mu.Lock()
+	samples := g.samples
+	g.samples = nil
+	g.mu.Unlock()
+
+	if len(samples) == 0 {
+		values, err := g.Generator.Generate()
+		if err != nil {
+			return nil, err
+		}
+		samples = make(map[string][]float64, len(values))
+		for name, value := range values {
+			samples[name] = []float64{value}
+		}
+	}
+
+	ret := make(Values, len(samples)*(len(g.Aggregations)+1))
+	for name, xs := range samples {
+		ret[name] = aggregate("avg", xs)
+		for _, a := range g.Aggregations {
+			ret[pluginPrefix+name+"."+a] = aggregate(a, xs)
+		}
+	}
+	return ret, nil
+}
+
+// samplingGraphDefs are the graphs of the aggregations for each family of
+// metrics, whose metrics are the aggregations.
+var samplingGraphDefs = map[string]map[string]CustomGraphDef{
+	"loadavg": {
+		"loadavg1":  {Label: "Sampled Loadavg 1 min", Unit: "float"},
+		"loadavg5":  {Label: "Sampled Loadavg 5 min", Unit: "float"},
+		"loadavg15": {Label: "Sampled Loadavg 15 min", Unit: "float"},
+	},
+	"cpu": {
+		"cpu.#.percentage": {Label: "Sampled CPU %1", Unit: "percentage"},
+	},
+	"memory": {
+		"memory.#": {Label: "Sampled Memory %1", Unit: "bytes"},
+	},
+	"interface": {
+		"interface.#.#.delta": {Label: "Sampled Interface %1 %2", Unit: "bytes/sec"},
+	},
+	"disk": {
+		"disk.#.#.delta": {Label: "Sampled Disk %1 %2", Unit: "iops"},
+	},
+	"filesystem": {
+		"filesystem.#.#": {Label: "Sampled Filesystem %1 %2", Unit: "bytes"},
+	},
+}
+
+var samplingAggregationLabels = map[string]string{
+	"max": "Max",
+	"min": "Min",
+	"p95": "95th percentile",
+}
+
+// PrepareGraphDefs returns the graph definitions of the aggregations.
+func (g *SamplingGenerator) PrepareGraphDefs() ([]*mkr.GraphDefsParam, error) {
+	if len(g.Aggregations) == 0 {
+		return nil, nil
+	}
+	metrics := make([]CustomGraphMetricDef, 0, len(g.Aggregations))
+	for _, a := range g.Aggregations {
+		metrics = append(metrics, CustomGraphMetricDef{Name: a, Label: samplingAggregationLabels[a]})
+	}
+	graphs := make(map[string]CustomGraphDef, len(samplingGraphDefs[g.Family]))
+	for name, graph := range samplingGraphDefs[g.Family] {
+		graph.Metrics = metrics
+		graphs[name] = graph
+	}
+	return NewGraphDefsParam(graphs), nil
+}
+
+func aggregate(aggregation string, xs []float64) float64 {
+	switch aggregation {
+	case "max":
+		v := xs[0]
+		for _, x := range xs[1:] {
+			v = math.Max(v, x)
+		}
+		return v
+	case "min":
+		v := xs[0]
+		for _, x := range xs[1:] {
+			v = math.Min(v, x)
+		}
+		return v
+	case "p95":
+		sorted := append([]float64(nil), xs...)
+		sort.Float64s(sorted)
+		// nearest-rank method
+		return sorted[int(math.Ceil(0.95*float64(len(sorted))))-1]
+	default:
+		var sum float64
+		for _, x := range xs {
+			sum += x
+		}
+		return sum / float64(len(xs))
+	}
+}
@@ -0,0 +1,130 @@
+package metrics
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type sequenceGenerator struct {
+	values []float64
+	i      int
+}
+
+func (g *sequenceGenerator) Generate() (Values, error) {
+	v := g.values[g.i%len(g.values)]
+	g.i++
+	return Values{"test.value": v}, nil
+}
+
+func TestSamplingGenerator(t *testing.T) {
+	g := &SamplingGenerator{
+		Generator:    &sequenceGenerator{values: []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}},
+		Aggregations: []string{"max", "min", "p95"},
+	}
+	for i := 0; i < 10; i++ {
+		v, _ := g.Generator.Generate()
+		g.add(v)
+	}
+
+	values, err := g.Generate()
+	if err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+	expect := Values{
+		"test.value":            5.5,
+		"custom.test.value.max": 10,
+		"custom.test.value.min": 1,
+		"custom.test.value.p95": 10,
+	}
+	if !reflect.DeepEqual(values, expect) {
+		t.Errorf("values should be %v but %v", expect, values)
+	}
+
+	// Without samples, Generate samples values immediately.
+	values, _ = g.Generate()
+	expect = Values{
+		"test.value":            1,
+		"custom.test.value.max": 1,
+		"custom.test.value.min": 1,
+		"custom.test.value.p95": 1,
+	}
+	if !reflect.DeepEqual(values, expect) {
+		t.Errorf("values should be %v but %v", expect, values)
+	}
+}
+
+func TestSamplingGenerator_PrepareGraphDefs(t *testing.T) {
+	g := &SamplingGenerator{
+		Family:       "interface",
+		Generator:    &sequenceGenerator{values: []float64{1}},
+		Aggregations: []string{"max", "p95"},
+	}
+	graphs, err := g.PrepareGraphDefs()
+	if err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+	if len(graphs) != 1 {
+		t.Fatalf("a graph should be defined for interface but %d", len(graphs))
+	}
+	graph := graphs[0]
+	if graph.Name != "custom.interface.#.#.delta" || graph.Unit != "bytes/sec" {
+		t.Errorf("unexpected graph: %+v", graph)
+	}
+	var names []string
+	for _, m := range graph.Metrics {
+		names = append(names, m.Name)
+	}
+	expect := []string{"custom.interface.#.#.delta.max", "custom.interface.#.#.delta.p95"}
+	if !reflect.DeepEqual(names, expect) {
+		t.Errorf("metrics should be %v but %v", expect, names)
+	}
+
+	// The aggregations of interface.eth0.rxBytes.delta should be posted as
+	// the metrics of the graph.
+	g.Generator = &keyGenerator{key: "interface.eth0.rxBytes.delta"}
+	values, _ := g.Generate()
+	for _, name := range expect {
+		key := strings.Replace(name, "#.#", "eth0.rxBytes", 1)
+		if _, ok := values[key]; !ok {
+			t.Errorf("%s should be posted: %v", key, values)
+		}
+	}
+
+	g.Aggregations = nil
+	if graphs, _ := g.PrepareGraphDefs(); graphs != nil {
+		t.Errorf("no graphs should be defined without aggregations: %v", graphs)
+	}
+}
+
+type keyGenerator struct {
+	key string
+}
+
+func (g *keyGenerator) Generate() (Values, error) {
+	return Values{g.key: 1}, nil
+}
+
+func TestSamplingGenerator_Run(t *testing.T) {
+	g := &SamplingGenerator{
+		Generator: &sequenceGenerator{values: []float64{2, 4}},
+		Interval:  10 * time.Millisecond,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go g.Run(ctx)
+	time.Sleep(55 * time.Millisecond)
+	cancel()
+
+	g.mu.Lock()
+	n := len(g.samples["test.value"])
+	g.mu.Unlock()
+	if n < 2 {
+		t.Errorf("values should be sampled several times but %d", n)
+	}
+	values, _ := g.Generate()
+	if v := values["test.value"]; v < 2 || v > 4 {
+		t.Errorf("test.value should be the average of samples but %f", v)
+	}
+}
@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"errors"
+
+	"github.com/mackerelio/golib/logging"
+	"github.com/mackerelio/mackerel-agent/util"
+	mkr "github.com/mackerelio/mackerel-client-go"
+)
+
+/*
+collect the state of the synchronization of the system clock
+
+`custom.timesync.synchronized.status`: 1 if the clock is synchronized by NTP daemons, otherwise 0, retrieved with adjtimex(2) (Linux only)
+
+`custom.timesync.error.{estimated,max}`: The estimated error and the maximum error of the clock in seconds (Linux only)
+
+`custom.timesync.offset.api`: The offset of the local clock from the Date header of the responses of Mackerel API in seconds,
+which is positive if the local clock is ahead (only if APIOffset is enabled).
+Since the header is truncated to seconds, the offset is accurate only to about one second.
+*/
+
+var timeSyncLogger = logging.GetLogger("metrics.timesync")
+
+// TimeSyncGenerator generates the state of the synchronization of the system clock
+type TimeSyncGenerator struct {
+	Stats     *AgentStats
+	APIOffset bool
+}
+
+// Generate the state of the synchronization of the system clock
+func (g *TimeSyncGenerator) Generate() (Values, error) {
+	ret := make(Values)
+	ts, err := util.ReadTimeSync()
+	switch {
+	case errors.Is(err, util.ErrTimeSyncNotSupported):
+		timeSyncLogger.Debugf("%s", err)
+	case err != nil:
+		timeSyncLogger.Errorf("Failed to read the state of time synchronization: %s", err)
+		return nil, err
+	default:
+		ret["custom.timesync.synchronized.status"] = 0
+		if ts.Synchronized {
+			ret["custom.timesync.synchronized.status"] = 1
+		}
+		ret["custom.timesync.error.estimated"] = ts.EstimatedError.Seconds()
+		ret["custom.timesync.error.max"] = ts.MaxError.Seconds()
+	}
+	if g.APIOffset {
+		if offset, ok := g.Stats.ClockOffset(); ok {
+			ret["custom.timesync.offset.api"] = offset.Seconds()
+		}
+	}
+	return ret, nil
+}
+
+// CustomIdentifier for PluginGenerator interface
+func (g *TimeSyncGenerator) CustomIdentifier() *string {
+	return nil
+}
+
+// PrepareGraphDefs for PluginGenerator interface
+func (g *TimeSyncGenerator) PrepareGraphDefs() ([]*mkr.GraphDefsParam, error) {
+	return NewGraphDefsParam(map[string]CustomGraphDef{
+		"timesync.synchronized": {
+			Label: "Time Synchronization Status",
+			Unit:  "integer",
+			Metrics: []CustomGraphMetricDef{
+				{Name: "status", Label: "Synchronized"},
+			},
+		},
+		"timesync.error": {
+			Label: "Time Synchronization Error",
+			Unit:  "float",
+			Metrics: []CustomGraphMetricDef{
+				{Name: "estimated", Label: "Estimated"},
+				{Name: "max", Label: "Max"},
+			},
+		},
+		"timesync.offset": {
+			Label: "Clock Offset",
+			Unit:  "float",
+			Metrics: []CustomGraphMetricDef{
+				{Name: "api", Label: "Mackerel API"},
+			},
+		},
+	}), nil
+}
@@ -0,0 +1,28 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeSyncGenerator(t *testing.T) {
+	stats := &AgentStats{}
+	g := &TimeSyncGenerator{Stats: stats, APIOffset: true}
+	values, err := g.Generate()
+	if err != nil {
+		t.Errorf("should not raise error: %v", err)
+	}
+	if _, ok := values["custom.timesync.offset.api"]; ok {
+		t.Errorf("offset should not be reported before observed: %v", values)
+	}
+
+	stats.RecordClockOffset(-1500 * time.Millisecond)
+	values, err = g.Generate()
+	if err != nil {
+		t.Errorf("should not raise error: %v", err)
+	}
+	if v := values["custom.timesync.offset.api"]; v != -1.5 {
+		t.Errorf("offset should be -1.5 but %v", v)
+	}
+	t.Logf("timesync metrics: %+v", values)
+}
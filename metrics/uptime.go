@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/mackerelio/golib/logging"
+	"github.com/mackerelio/mackerel-agent/util"
+	mkr "github.com/mackerelio/mackerel-client-go"
+)
+
+/*
+collect the uptime of the host
+
+`custom.uptime.seconds`: The seconds since the system booted, retrieved from btime in /proc/stat on Linux
+*/
+
+var uptimeLogger = logging.GetLogger("metrics.uptime")
+
+// UptimeGenerator generates the uptime of the host
+type UptimeGenerator struct {
+}
+
+// Generate the uptime of the host
+func (g *UptimeGenerator) Generate() (Values, error) {
+	bt, err := util.ReadBootTime()
+	if err != nil {
+		uptimeLogger.Errorf("Failed to read the boot time (skip these metrics): %s", err)
+		return nil, err
+	}
+	return Values{"custom.uptime.seconds": time.Since(bt).Seconds()}, nil
+}
+
+// CustomIdentifier for PluginGenerator interface
+func (g *UptimeGenerator) CustomIdentifier() *string {
+	return nil
+}
+
+// PrepareGraphDefs for PluginGenerator interface
+func (g *UptimeGenerator) PrepareGraphDefs() ([]*mkr.GraphDefsParam, error) {
+	return NewGraphDefsParam(map[string]CustomGraphDef{
+		"uptime": {
+			Label: "Uptime",
+			Unit:  "float",
+			Metrics: []CustomGraphMetricDef{
+				{Name: "seconds", Label: "Seconds"},
+			},
+		},
+	}), nil
+}
@@ -0,0 +1,14 @@
+package metrics
+
+import "testing"
+
+func TestUptimeGenerator(t *testing.T) {
+	g := &UptimeGenerator{}
+	values, err := g.Generate()
+	if err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+	if v, ok := values["custom.uptime.seconds"]; !ok || v <= 0 {
+		t.Errorf("uptime should be positive: %v", values)
+	}
+}
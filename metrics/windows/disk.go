@@ -15,16 +15,18 @@ import (
 )
 
 // DiskGenerator XXX
+// The Win32_PerfFormattedData classes provide rates already calculated by
+// WMI from its own previous sample, so Generate does not need to wait for
+// an interval and can be called at any period.
 type DiskGenerator struct {
 	IgnoreRegexp *regexp.Regexp
-	Interval     time.Duration
 }
 
 var diskLogger = logging.GetLogger("metrics.disk")
 
 // NewDiskGenerator XXX
-func NewDiskGenerator(ignoreReg *regexp.Regexp, interval time.Duration) (*DiskGenerator, error) {
-	return &DiskGenerator{ignoreReg, interval}, nil
+func NewDiskGenerator(ignoreReg *regexp.Regexp) (*DiskGenerator, error) {
+	return &DiskGenerator{ignoreReg}, nil
 }
 
 type win32PerfFormattedDataPerfDiskPhysicalDisk struct {
@@ -35,8 +37,6 @@ type win32PerfFormattedDataPerfDiskPhysicalDisk struct {
 
 // Generate XXX
 func (g *DiskGenerator) Generate() (metrics.Values, error) {
-	time.Sleep(g.Interval)
-
 	records, err := g.queryWmiWithTimeout()
 	if err != nil {
 		return nil, err
@@ -5,11 +5,10 @@ package windows
 
 import (
 	"testing"
-	"time"
 )
 
 func TestDiskGenerator(t *testing.T) {
-	g, err := NewDiskGenerator(nil, 1*time.Second)
+	g, err := NewDiskGenerator(nil)
 	if err != nil {
 		t.Errorf("should not raise error: %v", err)
 	}
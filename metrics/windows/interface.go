@@ -10,7 +10,6 @@ import (
 	"sort"
 	"strings"
 	"syscall"
-	"time"
 
 	"github.com/mackerelio/golib/logging"
 	"github.com/mackerelio/mackerel-agent/metrics"
@@ -18,9 +17,11 @@ import (
 )
 
 // InterfaceGenerator XXX
+// The PDH query keeps the previous sample, which is first collected in
+// NewInterfaceGenerator, and the rate counters are calculated against it
+// on each Generate.
 type InterfaceGenerator struct {
 	IgnoreRegexp *regexp.Regexp
-	Interval     time.Duration
 	query        syscall.Handle
 	counters     []*windows.CounterInfo
 }
@@ -37,8 +38,8 @@ func normalizeName(s string) string {
 }
 
 // NewInterfaceGenerator XXX
-func NewInterfaceGenerator(ignoreReg *regexp.Regexp, interval time.Duration) (*InterfaceGenerator, error) {
-	g := &InterfaceGenerator{ignoreReg, interval, 0, nil}
+func NewInterfaceGenerator(ignoreReg *regexp.Regexp) (*InterfaceGenerator, error) {
+	g := &InterfaceGenerator{ignoreReg, 0, nil}
 
 	var err error
 	g.query, err = windows.CreateQuery()
@@ -144,9 +145,6 @@ func NewInterfaceGenerator(ignoreReg *regexp.Regexp, interval time.Duration) (*I
 
 // Generate XXX
 func (g *InterfaceGenerator) Generate() (metrics.Values, error) {
-
-	time.Sleep(g.Interval)
-
 	r, _, err := windows.PdhCollectQueryData.Call(uintptr(g.query))
 	if r != 0 && err != nil {
 		if r == windows.PDH_NO_DATA {
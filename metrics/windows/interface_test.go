@@ -10,9 +10,7 @@ import (
 func TestInterfaceGenerator(t *testing.T) {
 	/*
 
-		g, _ := NewInterfaceGenerator(5)
-
-		t.Logf("interval '%s'", g.Interval)
+		g, _ := NewInterfaceGenerator(nil)
 
 		_, err := g.Generate()
 		if err != nil {
@@ -0,0 +1,69 @@
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"math"
+	"runtime"
+	"strconv"
+
+	"github.com/mackerelio/golib/logging"
+	"github.com/mackerelio/mackerel-agent/util"
+	"github.com/mackerelio/mackerel-client-go"
+)
+
+var cgroupLogger = logging.GetLogger("spec.cgroup")
+
+// CgroupCPUGenerator collects the CPU specs of the host as many as the CPU
+// quota of the cgroup, rounded up, so that the number of CPUs matches the
+// CPU metrics of the cgroup.
+type CgroupCPUGenerator struct {
+	Cgroup *util.Cgroup
+}
+
+// Generate cpu specs
+func (g *CgroupCPUGenerator) Generate() (any, error) {
+	value, err := (&CPUGenerator{}).Generate()
+	if err != nil {
+		return nil, err
+	}
+	quota, err := g.Cgroup.CPUQuota(runtime.NumCPU())
+	if err != nil {
+		cgroupLogger.Errorf("Failed to read cpu.max (skip this spec): %s", err)
+		return nil, err
+	}
+	return limitCPUSpec(value.(mackerel.CPU), quota), nil
+}
+
+func limitCPUSpec(cpu mackerel.CPU, quota float64) mackerel.CPU {
+	if n := int(math.Ceil(quota)); n < len(cpu) {
+		return cpu[:n]
+	}
+	return cpu
+}
+
+// CgroupMemoryGenerator collects the memory specs of the host, with the total
+// replaced by memory.max of the cgroup if limited, as the memory metrics of
+// the cgroup do.
+type CgroupMemoryGenerator struct {
+	Cgroup *util.Cgroup
+}
+
+// Generate memory specs
+func (g *CgroupMemoryGenerator) Generate() (any, error) {
+	value, err := (&MemoryGenerator{}).Generate()
+	if err != nil {
+		return nil, err
+	}
+	limit, limited, err := g.Cgroup.MemoryLimit("memory.max")
+	if err != nil {
+		cgroupLogger.Errorf("Failed to read memory.max (skip this spec): %s", err)
+		return nil, err
+	}
+	memory := value.(mackerel.Memory)
+	if limited {
+		memory["total"] = strconv.FormatUint(limit/1024, 10) + "kB"
+	}
+	return memory, nil
+}
@@ -0,0 +1,54 @@
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mackerelio/mackerel-agent/util"
+	"github.com/mackerelio/mackerel-client-go"
+)
+
+func TestLimitCPUSpec(t *testing.T) {
+	cpu := mackerel.CPU{{"core_id": "0"}, {"core_id": "1"}, {"core_id": "2"}, {"core_id": "3"}}
+	tests := []struct {
+		quota  float64
+		expect int
+	}{
+		{quota: 4, expect: 4},
+		{quota: 2, expect: 2},
+		{quota: 0.5, expect: 1},
+		{quota: 1.5, expect: 2},
+	}
+	for _, tt := range tests {
+		if n := len(limitCPUSpec(cpu, tt.quota)); n != tt.expect {
+			t.Errorf("number of CPUs with quota %v should be %d but %d", tt.quota, tt.expect, n)
+		}
+	}
+}
+
+func TestCgroupMemoryGenerator(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "ctr"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "ctr", "memory.max"), []byte("536870912\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	g := &CgroupMemoryGenerator{Cgroup: &util.Cgroup{Root: root, Path: "/ctr"}}
+	value, err := g.Generate()
+	if err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+	memory := value.(mackerel.Memory)
+	if memory["total"] != "524288kB" {
+		t.Errorf("total should be memory.max but %q", memory["total"])
+	}
+	if _, ok := memory["swap_total"]; !ok {
+		t.Errorf("the other specs should be of the host: %v", memory)
+	}
+}
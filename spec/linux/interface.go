@@ -4,9 +4,15 @@
 package linux
 
 import (
+	"context"
+	"errors"
+	"net"
+	"sort"
+
 	"github.com/vishvananda/netlink"
 
 	"github.com/mackerelio/mackerel-agent/spec"
+	"github.com/mackerelio/mackerel-agent/util"
 	mkr "github.com/mackerelio/mackerel-client-go"
 )
 
@@ -66,3 +72,80 @@ func (g *InterfaceGenerator) generateByNetLink() (spec.Interfaces, error) {
 	}
 	return interfaces, nil
 }
+
+// Links returns the link states of the network interfaces except loopback.
+// Speed and duplex are retrieved from sysfs, and the status of the slaves
+// of bonding interfaces from /proc/net/bonding.
+func (g *InterfaceGenerator) Links() ([]spec.Link, error) {
+	links, err := netlink.LinkList()
+	if err != nil {
+		return nil, err
+	}
+	bonds, err := util.ReadBonds(util.BondingPath)
+	if err != nil {
+		return nil, err
+	}
+	bondsByName := make(map[string]*util.Bond)
+	for _, bond := range bonds {
+		bondsByName[bond.Name] = bond
+	}
+	names := make(map[int]string)
+	for _, link := range links {
+		names[link.Attrs().Index] = link.Attrs().Name
+	}
+
+	var results []spec.Link
+	for _, link := range links {
+		attr := link.Attrs()
+		if attr.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		l := spec.Link{
+			Name:   attr.Name,
+			State:  attr.OperState.String(),
+			MTU:    attr.MTU,
+			Master: names[attr.MasterIndex],
+			Bond:   bondsByName[attr.Name],
+		}
+		if status, err := util.ReadLinkStatus(util.SysClassNetPath, attr.Name); err == nil {
+			l.Speed, l.Duplex = status.Speed, status.Duplex
+		}
+		results = append(results, l)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Name < results[j].Name
+	})
+	return results, nil
+}
+
+// Watch calls notify on each netlink event of addresses and links until ctx is done.
+func (g *InterfaceGenerator) Watch(ctx context.Context, notify func()) error {
+	// stop the other subscription when one of them fails
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	addrCh := make(chan netlink.AddrUpdate, 16)
+	if err := netlink.AddrSubscribe(addrCh, ctx.Done()); err != nil {
+		return err
+	}
+	linkCh := make(chan netlink.LinkUpdate, 16)
+	if err := netlink.LinkSubscribe(linkCh, ctx.Done()); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case _, ok := <-addrCh:
+			if !ok {
+				return errors.New("the subscription of netlink address events is closed")
+			}
+			notify()
+		case _, ok := <-linkCh:
+			if !ok {
+				return errors.New("the subscription of netlink link events is closed")
+			}
+			notify()
+		}
+	}
+}
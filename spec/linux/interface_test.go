@@ -4,8 +4,10 @@
 package linux
 
 import (
+	"context"
 	"os"
 	"testing"
+	"time"
 )
 
 func TestInterfaceGenerate(t *testing.T) {
@@ -37,3 +39,29 @@ func TestInterfaceGenerate(t *testing.T) {
 		t.Error("interface should have macAddress")
 	}
 }
+
+func TestInterfaceLinks(t *testing.T) {
+	g := &InterfaceGenerator{}
+	links, err := g.Links()
+	if err != nil {
+		t.Errorf("should not raise error: %v", err)
+	}
+	for _, link := range links {
+		if link.Name == "lo" {
+			t.Error("links should not contain loopback")
+		}
+		if link.State == "" {
+			t.Errorf("link %s should have state", link.Name)
+		}
+	}
+	t.Logf("links: %+v", links)
+}
+
+func TestInterfaceWatch(t *testing.T) {
+	g := &InterfaceGenerator{}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := g.Watch(ctx, func() {}); err != nil && ctx.Err() == nil {
+		t.Errorf("should not raise error until canceled: %v", err)
+	}
+}
@@ -1,8 +1,10 @@
 package spec
 
 import (
+	"context"
 	"net"
 
+	"github.com/mackerelio/mackerel-agent/util"
 	mkr "github.com/mackerelio/mackerel-client-go"
 )
 
@@ -67,3 +69,27 @@ func (ifs Interfaces) AppendIPv6Address(name, addr string) {
 type InterfaceGenerator interface {
 	Generate() ([]mkr.Interface, error)
 }
+
+// Link is the link state of a network interface, which is posted as the host
+// metadata since mkr.Interface has no fields for it.
+type Link struct {
+	Name   string     `json:"name"`
+	State  string     `json:"state"`
+	MTU    int        `json:"mtu"`
+	Speed  int        `json:"speed,omitempty"` // Mbps
+	Duplex string     `json:"duplex,omitempty"`
+	Master string     `json:"master,omitempty"`
+	Bond   *util.Bond `json:"bond,omitempty"`
+}
+
+// LinkGenerator retrieve link states of network interfaces
+type LinkGenerator interface {
+	Links() ([]Link, error)
+}
+
+// InterfaceWatcher watches changes of network interfaces such as
+// addresses and link states, and calls notify on each change until
+// ctx is done.
+type InterfaceWatcher interface {
+	Watch(ctx context.Context, notify func()) error
+}
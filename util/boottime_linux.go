@@ -0,0 +1,41 @@
+//go:build linux
+// +build linux
+
+package util
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReadBootTime returns the time when the system booted, retrieved from
+// btime in /proc/stat.
+func ReadBootTime() (time.Time, error) {
+	out, err := os.ReadFile("/proc/stat")
+	if err != nil {
+		return time.Time{}, err
+	}
+	return parseBootTime(out)
+}
+
+func parseBootTime(out []byte) (time.Time, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		if v, ok := strings.CutPrefix(scanner.Text(), "btime "); ok {
+			sec, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+			if err != nil {
+				return time.Time{}, err
+			}
+			return time.Unix(sec, 0), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return time.Time{}, err
+	}
+	return time.Time{}, errors.New("btime not found in /proc/stat")
+}
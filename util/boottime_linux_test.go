@@ -0,0 +1,38 @@
+//go:build linux
+// +build linux
+
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReadBootTime(t *testing.T) {
+	bt, err := ReadBootTime()
+	if err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+	if bt.IsZero() || bt.After(time.Now()) {
+		t.Errorf("unexpected boot time: %s", bt)
+	}
+}
+
+func TestParseBootTime(t *testing.T) {
+	out := []byte(`cpu  4705 356 584 3699176 23060 0 277 0 0 0
+intr 114930548 113199788 3 0 5 263 0 4
+ctxt 1990473
+btime 1062191376
+processes 2915
+`)
+	bt, err := parseBootTime(out)
+	if err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+	if bt.Unix() != 1062191376 {
+		t.Errorf("boot time should be 1062191376 but %d", bt.Unix())
+	}
+	if _, err := parseBootTime([]byte("ctxt 1990473\n")); err == nil {
+		t.Error("should raise error without btime")
+	}
+}
@@ -0,0 +1,19 @@
+//go:build !linux
+// +build !linux
+
+package util
+
+import (
+	"time"
+
+	"github.com/shirou/gopsutil/v3/host"
+)
+
+// ReadBootTime returns the time when the system booted.
+func ReadBootTime() (time.Time, error) {
+	sec, err := host.BootTime()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(int64(sec), 0), nil
+}
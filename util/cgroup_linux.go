@@ -0,0 +1,176 @@
+//go:build linux
+// +build linux
+
+package util
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// CgroupRoot is the mount point of the cgroup v2 unified hierarchy.
+const CgroupRoot = "/sys/fs/cgroup"
+
+// Cgroup is a cgroup v2 directory of the agent process.
+type Cgroup struct {
+	Root string // the mount point of the unified hierarchy
+	Path string // the path relative to Root, "/" for the root cgroup
+}
+
+// DetectCgroupV2 detects the cgroup v2 which the agent process belongs to.
+// It fails if the unified hierarchy is not mounted on CgroupRoot.
+func DetectCgroupV2() (*Cgroup, error) {
+	out, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return nil, err
+	}
+	return detectCgroupV2(CgroupRoot, out)
+}
+
+// `/proc/self/cgroup` sample:
+//  0::/system.slice/mackerel-agent.service
+// The hierarchy ID is 0 and the controller list is empty for cgroup v2.
+
+func detectCgroupV2(root string, procSelfCgroup []byte) (*Cgroup, error) {
+	if _, err := os.Stat(filepath.Join(root, "cgroup.controllers")); err != nil {
+		return nil, fmt.Errorf("cgroup v2 is not mounted on %s: %w", root, err)
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(procSelfCgroup))
+	for scanner.Scan() {
+		path, ok := strings.CutPrefix(scanner.Text(), "0::")
+		if !ok {
+			continue
+		}
+		// Without the cgroup namespace, the path is of the host and
+		// the cgroup may not be visible in the container.
+		if _, err := os.Stat(filepath.Join(root, path)); err != nil {
+			path = "/"
+		}
+		return &Cgroup{Root: root, Path: path}, nil
+	}
+	return nil, errors.New("the process does not belong to cgroup v2")
+}
+
+// Dir returns the directory of the cgroup.
+func (c *Cgroup) Dir() string {
+	return filepath.Join(c.Root, c.Path)
+}
+
+// ReadFile reads the interface file of the cgroup.
+func (c *Cgroup) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(c.Dir(), name))
+}
+
+// ReadKeyedValues reads a flat keyed file such as cpu.stat and memory.stat.
+func (c *Cgroup) ReadKeyedValues(name string) (map[string]uint64, error) {
+	out, err := c.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return parseCgroupKeyedValues(out), nil
+}
+
+func parseCgroupKeyedValues(out []byte) map[string]uint64 {
+	values := make(map[string]uint64)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		v, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		values[fields[0]] = v
+	}
+	return values
+}
+
+// ReadLimits reads the interface file of the cgroup and its ancestors,
+// such as memory.max, since the limits of the ancestors also apply.
+// The contents are returned from the cgroup to the root. The cgroups
+// without the file, e.g. the root cgroup, are skipped.
+func (c *Cgroup) ReadLimits(name string) ([]string, error) {
+	var limits []string
+	for path := c.Path; ; path = filepath.Dir(path) {
+		out, err := os.ReadFile(filepath.Join(c.Root, path, name))
+		if err == nil {
+			limits = append(limits, strings.TrimSpace(string(out)))
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		if path == "/" || path == "." {
+			break
+		}
+	}
+	return limits, nil
+}
+
+// CPUQuota returns the number of CPUs available to the cgroup, that is,
+// the smallest quota in cpu.max of the cgroup and its ancestors, or numCPU
+// if unlimited.
+func (c *Cgroup) CPUQuota(numCPU int) (float64, error) {
+	limits, err := c.ReadLimits("cpu.max")
+	if err != nil {
+		return 0, err
+	}
+	return cgroupCPUQuota(limits, numCPU), nil
+}
+
+// cgroupCPUQuota returns the number of CPUs available to the cgroup from
+// the contents of cpu.max ("$MAX $PERIOD", where $MAX may be "max").
+func cgroupCPUQuota(limits []string, numCPU int) float64 {
+	quota := float64(numCPU)
+	for _, limit := range limits {
+		fields := strings.Fields(limit)
+		if len(fields) != 2 || fields[0] == "max" {
+			continue
+		}
+		max, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			continue
+		}
+		period, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil || period <= 0 {
+			continue
+		}
+		quota = math.Min(quota, max/period)
+	}
+	return quota
+}
+
+// MemoryLimit returns the smallest limit in the interface file such as
+// memory.max and memory.swap.max of the cgroup and its ancestors, and whether
+// the memory is limited.
+func (c *Cgroup) MemoryLimit(name string) (uint64, bool, error) {
+	limits, err := c.ReadLimits(name)
+	if err != nil {
+		return 0, false, err
+	}
+	limit, limited := cgroupMemoryLimit(limits)
+	return limit, limited, nil
+}
+
+// cgroupMemoryLimit returns the smallest limit in the contents of memory.max
+// or memory.swap.max, and whether the memory is limited.
+func cgroupMemoryLimit(limits []string) (uint64, bool) {
+	var limit uint64 = math.MaxUint64
+	limited := false
+	for _, s := range limits {
+		v, err := strconv.ParseUint(s, 10, 64)
+		if err != nil { // "max"
+			continue
+		}
+		limit = min(limit, v)
+		limited = true
+	}
+	return limit, limited
+}
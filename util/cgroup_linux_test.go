@@ -0,0 +1,105 @@
+//go:build linux
+// +build linux
+
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeCgroupFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDetectCgroupV2(t *testing.T) {
+	root := t.TempDir()
+	procSelfCgroup := []byte("0::/system.slice/mackerel-agent.service\n")
+
+	if _, err := detectCgroupV2(root, procSelfCgroup); err == nil {
+		t.Error("should raise error without cgroup.controllers")
+	}
+
+	writeCgroupFiles(t, root, map[string]string{
+		"cgroup.controllers":                             "cpu io memory pids\n",
+		"system.slice/mackerel-agent.service/memory.max": "max\n",
+	})
+	cg, err := detectCgroupV2(root, procSelfCgroup)
+	if err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+	if cg.Path != "/system.slice/mackerel-agent.service" {
+		t.Errorf("unexpected path: %s", cg.Path)
+	}
+
+	// in a container without the cgroup namespace
+	cg, err = detectCgroupV2(root, []byte("0::/docker/0123456789ab\n"))
+	if err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+	if cg.Path != "/" {
+		t.Errorf("path should fall back to the root: %s", cg.Path)
+	}
+
+	// cgroup v1
+	if _, err := detectCgroupV2(root, []byte("4:memory:/user.slice\n")); err == nil {
+		t.Error("should raise error for cgroup v1")
+	}
+}
+
+func TestCgroupReadLimits(t *testing.T) {
+	root := t.TempDir()
+	writeCgroupFiles(t, root, map[string]string{
+		"kubepods/memory.max":           "1073741824\n",
+		"kubepods/pod1/memory.max":      "max\n",
+		"kubepods/pod1/ctr1/memory.max": "536870912\n",
+		"kubepods/pod1/ctr1/cpu.stat":   "usage_usec 100\nuser_usec 60\nsystem_usec 40\n",
+	})
+	cg := &Cgroup{Root: root, Path: "/kubepods/pod1/ctr1"}
+
+	limits, err := cg.ReadLimits("memory.max")
+	if err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+	expect := []string{"536870912", "max", "1073741824"}
+	if !reflect.DeepEqual(limits, expect) {
+		t.Errorf("limits should be %v but %v", expect, limits)
+	}
+
+	values, err := cg.ReadKeyedValues("cpu.stat")
+	if err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+	if !reflect.DeepEqual(values, map[string]uint64{"usage_usec": 100, "user_usec": 60, "system_usec": 40}) {
+		t.Errorf("unexpected values: %v", values)
+	}
+}
+
+func TestCgroupCPUQuota(t *testing.T) {
+	tests := []struct {
+		limits []string
+		expect float64
+	}{
+		{limits: nil, expect: 8},
+		{limits: []string{"max 100000"}, expect: 8},
+		{limits: []string{"200000 100000"}, expect: 2},
+		{limits: []string{"max 100000", "50000 100000"}, expect: 0.5},
+		{limits: []string{"1600000 100000"}, expect: 8},
+	}
+	for _, tt := range tests {
+		if quota := cgroupCPUQuota(tt.limits, 8); quota != tt.expect {
+			t.Errorf("quota of %v should be %v but %v", tt.limits, tt.expect, quota)
+		}
+	}
+}
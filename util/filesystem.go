@@ -13,6 +13,7 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Songmu/timeout"
@@ -45,8 +46,9 @@ var dfHeaderPattern = regexp.MustCompile(
 var logger = logging.GetLogger("util.filesystem")
 
 var dfOpt = "-Pkl"
+var dfOptOnce sync.Once
 
-func init() {
+func detectDfOpt() {
 	// Some `df` command such as busybox does not have `-P` or `-l` option.
 	tio := &timeout.Timeout{
 		Cmd:       exec.Command("df", dfOpt),
@@ -59,8 +61,9 @@ func init() {
 	}
 }
 
-// CollectDfValues collects disk free statistics from df command
-func CollectDfValues() ([]*DfStat, error) {
+// collectDfValuesByCommand collects disk free statistics from df command
+func collectDfValuesByCommand() ([]*DfStat, error) {
+	dfOptOnce.Do(detectDfOpt)
 	cmd := exec.Command("df", dfOpt)
 	tio := &timeout.Timeout{
 		Cmd:       cmd,
@@ -94,14 +97,7 @@ func parseDfLines(out string) []*DfStat {
 			logger.Warningf(err.Error())
 			continue
 		}
-		// https://github.com/docker/docker/blob/v1.5.0/daemon/graphdriver/devmapper/deviceset.go#L981
-		if strings.HasPrefix(dfstat.Name, "/dev/mapper/docker-") {
-			continue
-		}
-		// https://debbugs.gnu.org/cgi/bugreport.cgi?bug=10363
-		// http://git.savannah.gnu.org/gitweb/?p=coreutils.git;a=commit;h=1e18d8416f9ef43bf08982cabe54220587061a08
-		// coreutils >= 8.15
-		if strings.HasPrefix(dfstat.Name, "/dev/dm-") && strings.Contains(dfstat.Mounted, "devicemapper/mnt") {
+		if isDockerDevicemapper(dfstat) {
 			continue
 		}
 
@@ -126,6 +122,17 @@ func parseDfLines(out string) []*DfStat {
 	return filesystems
 }
 
+func isDockerDevicemapper(dfstat *DfStat) bool {
+	// https://github.com/docker/docker/blob/v1.5.0/daemon/graphdriver/devmapper/deviceset.go#L981
+	if strings.HasPrefix(dfstat.Name, "/dev/mapper/docker-") {
+		return true
+	}
+	// https://debbugs.gnu.org/cgi/bugreport.cgi?bug=10363
+	// http://git.savannah.gnu.org/gitweb/?p=coreutils.git;a=commit;h=1e18d8416f9ef43bf08982cabe54220587061a08
+	// coreutils >= 8.15
+	return strings.HasPrefix(dfstat.Name, "/dev/dm-") && strings.Contains(dfstat.Mounted, "devicemapper/mnt")
+}
+
 var dfColumnsPattern = regexp.MustCompile(`^(.+?)\s+(\d+)\s+(\d+)\s+(\d+)\s+(\d+)%\s+(.+)$`)
 
 func parseDfLine(line string) (*DfStat, error) {
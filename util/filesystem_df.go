@@ -0,0 +1,9 @@
+//go:build darwin || freebsd || netbsd
+// +build darwin freebsd netbsd
+
+package util
+
+// CollectDfValues collects disk free statistics from df command
+func CollectDfValues() ([]*DfStat, error) {
+	return collectDfValuesByCommand()
+}
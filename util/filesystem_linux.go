@@ -0,0 +1,177 @@
+//go:build linux
+// +build linux
+
+package util
+
+import (
+	"fmt"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Filesystem types which are not collected by CollectDfValues by default,
+// in addition to the filesystems which have no blocks such as proc and sysfs.
+var defaultIgnoredFSTypes = map[string]bool{
+	// network filesystems (`df -l` does not show them)
+	"nfs": true, "nfs4": true, "cifs": true, "smb3": true, "smbfs": true, "ncpfs": true,
+	"afs": true, "ceph": true, "glusterfs": true, "lustre": true, "9p": true,
+	"fuse.sshfs": true, "fuse.glusterfs": true, "fuse.s3fs": true,
+	// pseudo filesystems
+	"autofs": true, "binfmt_misc": true, "bpf": true, "cgroup": true, "cgroup2": true,
+	"configfs": true, "debugfs": true, "devpts": true, "efivarfs": true, "fusectl": true,
+	"hugetlbfs": true, "mqueue": true, "nsfs": true, "proc": true, "pstore": true,
+	"rpc_pipefs": true, "securityfs": true, "selinuxfs": true, "sysfs": true, "tracefs": true,
+}
+
+var ignoredFSTypes = defaultIgnoredFSTypes
+
+// SetIgnoredFSTypes overrides the filesystem types which are not collected by
+// CollectDfValues. If types is nil, the default types, that is, network and
+// pseudo filesystems are ignored. It should be called before collecting.
+func SetIgnoredFSTypes(types []string) {
+	if types == nil {
+		ignoredFSTypes = defaultIgnoredFSTypes
+		return
+	}
+	ignoredFSTypes = make(map[string]bool, len(types))
+	for _, t := range types {
+		ignoredFSTypes[t] = true
+	}
+}
+
+// statfs(2) may hang on unresponsive filesystems.
+var statfsTimeout = 5 * time.Second
+
+// CollectDfValues collects disk free statistics of local filesystems
+// from /proc/self/mountinfo and statfs(2). The values are compatible with
+// the output of `df -Pkl`. It falls back to df command if mountinfo is not
+// available.
+func CollectDfValues() ([]*DfStat, error) {
+	mounts, err := CollectMountInfo()
+	if err != nil {
+		logger.Warningf("failed to read mountinfo, fall back to df command: %s", err)
+		return collectDfValuesByCommand()
+	}
+	return collectDfValuesByStatfs(mounts, Statfs), nil
+}
+
+func collectDfValuesByStatfs(mounts []*MountInfo, statfs func(string) (*syscall.Statfs_t, error)) []*DfStat {
+	// A filesystem mounted over another one hides it, so collect the
+	// visible filesystem of each mountpoint first.
+	var visible []*DfStat
+	var visibleDevices []string // the device of each of visible
+	mountpoints := make(map[string]int)
+	for _, m := range mounts {
+		if ignoredFSTypes[m.FSType] {
+			continue
+		}
+		st, err := statfs(m.Mountpoint)
+		if err != nil {
+			logger.Warningf("failed to statfs %s: %s", m.Mountpoint, err)
+			continue
+		}
+		if st.Blocks == 0 {
+			continue
+		}
+		dfstat := newDfStat(m, st)
+		if isDockerDevicemapper(dfstat) {
+			continue
+		}
+		if i, ok := mountpoints[dfstat.Mounted]; ok {
+			visible[i], visibleDevices[i] = dfstat, m.Device
+			continue
+		}
+		mountpoints[dfstat.Mounted] = len(visible)
+		visible = append(visible, dfstat)
+		visibleDevices = append(visibleDevices, m.Device)
+	}
+
+	// Show a device mounted on multiple mountpoints once as df does,
+	// preferring the shortest mountpoint.
+	var filesystems []*DfStat
+	devices := make(map[string]int)
+	for i, dfstat := range visible {
+		if j, ok := devices[visibleDevices[i]]; ok {
+			if len(dfstat.Mounted) < len(filesystems[j].Mounted) {
+				filesystems[j] = dfstat
+			}
+			continue
+		}
+		devices[visibleDevices[i]] = len(filesystems)
+		filesystems = append(filesystems, dfstat)
+	}
+	return filesystems
+}
+
+func newDfStat(m *MountInfo, st *syscall.Statfs_t) *DfStat {
+	// kilo bytes
+	blockSize := uint64(st.Frsize)
+	if blockSize == 0 {
+		blockSize = uint64(st.Bsize)
+	}
+	blocks := st.Blocks * blockSize / 1024
+	used := (st.Blocks - st.Bfree) * blockSize / 1024
+	available := st.Bavail * blockSize / 1024
+
+	// df rounds up the percentage of used blocks to non-root users.
+	var capacity uint64
+	if used+available > 0 {
+		capacity = (used*100 + used + available - 1) / (used + available)
+	}
+	return &DfStat{
+		Name:      m.Source,
+		Blocks:    blocks,
+		Used:      used,
+		Available: available,
+		Capacity:  uint8(capacity),
+		Mounted:   m.Mountpoint,
+	}
+}
+
+// The numbers of statfs calls which have timed out and not returned yet, by path.
+var hungStatfs = struct {
+	sync.Mutex
+	m map[string]int
+}{m: make(map[string]int)}
+
+var syscallStatfs = syscall.Statfs
+
+// Statfs calls statfs(2) for path with a timeout. While a timed out call for
+// path does not return, the following calls for the same path fail immediately
+// so as not to pile up goroutines hanging on an unresponsive filesystem.
+func Statfs(path string) (*syscall.Statfs_t, error) {
+	hungStatfs.Lock()
+	hung := hungStatfs.m[path] > 0
+	hungStatfs.Unlock()
+	if hung {
+		return nil, fmt.Errorf("previous statfs has not returned yet")
+	}
+	type result struct {
+		st  *syscall.Statfs_t
+		err error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		var st syscall.Statfs_t
+		err := syscallStatfs(path, &st)
+		ch <- result{&st, err}
+	}()
+	select {
+	case r := <-ch:
+		return r.st, r.err
+	case <-time.After(statfsTimeout):
+		hungStatfs.Lock()
+		hungStatfs.m[path]++
+		hungStatfs.Unlock()
+		go func() {
+			<-ch
+			hungStatfs.Lock()
+			if hungStatfs.m[path]--; hungStatfs.m[path] <= 0 {
+				delete(hungStatfs.m, path)
+			}
+			hungStatfs.Unlock()
+		}()
+		return nil, fmt.Errorf("statfs timed out after %s", statfsTimeout)
+	}
+}
@@ -0,0 +1,189 @@
+//go:build linux
+// +build linux
+
+package util
+
+import (
+	"fmt"
+	"reflect"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestCollectDfValuesByStatfs(t *testing.T) {
+	mounts := parseMountInfo([]byte(`22 28 0:21 / /sys rw,nosuid,nodev,noexec,relatime shared:7 - sysfs sysfs rw
+23 28 0:22 / /proc rw,nosuid,nodev,noexec,relatime shared:12 - proc proc rw
+28 1 8:1 / / rw,relatime shared:1 - ext4 /dev/sda1 rw,errors=remount-ro
+29 28 0:25 / /dev/shm rw,relatime - tmpfs tmpfs rw
+30 29 0:26 / /dev/shm rw,relatime - tmpfs tmpfs rw,size=1024k
+40 28 8:1 /srv /var/srv rw,relatime - ext4 /dev/sda1 rw
+41 28 0:50 / /mnt/nfs rw,relatime - nfs4 server:/export rw
+42 28 0:51 / /mnt/empty rw,relatime - tmpfs tmpfs rw
+43 28 8:2 / /stale rw,relatime - ext4 /dev/sdb1 rw
+44 28 8:3 / /data rw,relatime - ext4 /dev/sdc1 rw
+45 44 8:4 / /data rw,relatime - xfs /dev/sdd1 rw
+46 28 8:3 /backup /backup rw,relatime - ext4 /dev/sdc1 rw
+`))
+	statfs := func(path string) (*syscall.Statfs_t, error) {
+		switch path {
+		case "/":
+			return &syscall.Statfs_t{Frsize: 4096, Blocks: 1000, Bfree: 300, Bavail: 250}, nil
+		case "/var/srv":
+			return &syscall.Statfs_t{Frsize: 4096, Blocks: 1000, Bfree: 300, Bavail: 250}, nil
+		case "/dev/shm":
+			return &syscall.Statfs_t{Frsize: 1024, Blocks: 1024, Bfree: 1024, Bavail: 1024}, nil
+		case "/data", "/backup":
+			return &syscall.Statfs_t{Frsize: 1024, Blocks: 100, Bfree: 50, Bavail: 50}, nil
+		case "/mnt/empty", "/sys", "/proc":
+			return &syscall.Statfs_t{}, nil
+		}
+		return nil, fmt.Errorf("unexpected statfs: %s", path)
+	}
+
+	expect := []*DfStat{
+		{Name: "/dev/sda1", Blocks: 4000, Used: 2800, Available: 1000, Capacity: 74, Mounted: "/"},
+		{Name: "tmpfs", Blocks: 1024, Used: 0, Available: 1024, Capacity: 0, Mounted: "/dev/shm"},
+		{Name: "/dev/sdd1", Blocks: 100, Used: 50, Available: 50, Capacity: 50, Mounted: "/data"},
+		// sdc1 hidden by sdd1 on /data is shown on the other mountpoint.
+		{Name: "/dev/sdc1", Blocks: 100, Used: 50, Available: 50, Capacity: 50, Mounted: "/backup"},
+	}
+	filesystems := collectDfValuesByStatfs(mounts, statfs)
+	if !reflect.DeepEqual(filesystems, expect) {
+		for _, fs := range filesystems {
+			t.Logf("%+v", fs)
+		}
+		t.Errorf("dfvalues are not expected")
+	}
+}
+
+func TestCollectDfValuesByStatfs_mountedTwiceAndOver(t *testing.T) {
+	mounts := parseMountInfo([]byte(`28 1 8:1 / / rw,relatime shared:1 - ext4 /dev/sda1 rw
+40 28 8:16 / /srv/data rw,relatime - ext4 /dev/sdb1 rw
+41 28 8:16 / /data rw,relatime - ext4 /dev/sdb1 rw
+42 41 8:32 / /data rw,relatime - xfs /dev/sdc1 rw
+43 28 8:48 / /var/lib/app rw,relatime - ext4 /dev/sdd1 rw
+44 28 8:64 / /opt rw,relatime - ext4 /dev/sde1 rw
+45 44 8:48 / /opt rw,relatime - ext4 /dev/sdd1 rw
+`))
+	statfs := func(path string) (*syscall.Statfs_t, error) {
+		return &syscall.Statfs_t{Frsize: 1024, Blocks: 100, Bfree: 50, Bavail: 50}, nil
+	}
+
+	var mounted []string
+	for _, fs := range collectDfValuesByStatfs(mounts, statfs) {
+		mounted = append(mounted, fs.Name+" "+fs.Mounted)
+	}
+	expect := []string{
+		"/dev/sda1 /",
+		// sdb1 hidden by sdc1 on /data is shown on the other mountpoint.
+		"/dev/sdb1 /srv/data",
+		"/dev/sdc1 /data",
+		// sdd1 mounted over sde1 on /opt is shown once on the shorter mountpoint.
+		"/dev/sdd1 /opt",
+	}
+	if !reflect.DeepEqual(mounted, expect) {
+		t.Errorf("filesystems should be %q but %q", expect, mounted)
+	}
+}
+
+func TestSetIgnoredFSTypes(t *testing.T) {
+	defer SetIgnoredFSTypes(nil)
+	mounts := parseMountInfo([]byte(`28 1 8:1 / / rw,relatime shared:1 - ext4 /dev/sda1 rw
+41 28 0:50 / /mnt/nfs rw,relatime - nfs4 server:/export rw
+`))
+	statfs := func(path string) (*syscall.Statfs_t, error) {
+		return &syscall.Statfs_t{Frsize: 1024, Blocks: 100, Bfree: 50, Bavail: 50}, nil
+	}
+
+	SetIgnoredFSTypes([]string{"ext4"})
+	filesystems := collectDfValuesByStatfs(mounts, statfs)
+	if len(filesystems) != 1 || filesystems[0].Mounted != "/mnt/nfs" {
+		t.Errorf("only nfs4 should be collected: %+v", filesystems)
+	}
+
+	SetIgnoredFSTypes(nil)
+	filesystems = collectDfValuesByStatfs(mounts, statfs)
+	if len(filesystems) != 1 || filesystems[0].Mounted != "/" {
+		t.Errorf("nfs4 should be ignored by default: %+v", filesystems)
+	}
+}
+
+func TestStatfs(t *testing.T) {
+	st, err := Statfs("/")
+	if err != nil {
+		t.Fatalf("err should be nil but: %s", err)
+	}
+	if st.Blocks == 0 {
+		t.Errorf("blocks of / should not be 0")
+	}
+}
+
+func TestStatfs_concurrent(t *testing.T) {
+	defer func() { syscallStatfs = syscall.Statfs }()
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	syscallStatfs = func(path string, st *syscall.Statfs_t) error {
+		started <- struct{}{}
+		<-release
+		st.Blocks = 100
+		return nil
+	}
+
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := Statfs("/")
+			errs <- err
+		}()
+	}
+	<-started
+	<-started
+	close(release)
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("concurrent statfs should succeed but: %s", err)
+		}
+	}
+}
+
+func TestStatfs_hung(t *testing.T) {
+	defer func(d time.Duration) {
+		syscallStatfs = syscall.Statfs
+		statfsTimeout = d
+	}(statfsTimeout)
+	statfsTimeout = 10 * time.Millisecond
+	release := make(chan struct{})
+	returned := make(chan struct{})
+	var hung int32 = 1
+	syscallStatfs = func(path string, st *syscall.Statfs_t) error {
+		if atomic.LoadInt32(&hung) == 0 {
+			return nil
+		}
+		<-release
+		returned <- struct{}{}
+		return nil
+	}
+
+	if _, err := Statfs("/hung"); err == nil {
+		t.Fatalf("statfs should time out")
+	}
+	if _, err := Statfs("/hung"); err == nil || err.Error() != "previous statfs has not returned yet" {
+		t.Errorf("statfs should fail while the previous call hangs: %v", err)
+	}
+
+	atomic.StoreInt32(&hung, 0)
+	close(release)
+	<-returned
+	for i := 0; ; i++ {
+		_, err := Statfs("/hung")
+		if err == nil {
+			break
+		}
+		if i >= 100 {
+			t.Fatalf("statfs should succeed after the hung call returns: %s", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
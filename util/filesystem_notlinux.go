@@ -0,0 +1,8 @@
+//go:build !linux
+// +build !linux
+
+package util
+
+// SetIgnoredFSTypes does nothing on the platforms other than Linux, where
+// df command or the Windows API reports only local filesystems.
+func SetIgnoredFSTypes(types []string) {}
@@ -0,0 +1,146 @@
+package util
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SysClassNetPath is the directory of network interfaces in sysfs
+const SysClassNetPath = "/sys/class/net"
+
+// BondingPath is the directory of the status of bonding interfaces
+const BondingPath = "/proc/net/bonding"
+
+// LinkStatus is the status of a network interface in sysfs.
+type LinkStatus struct {
+	OperState string // e.g. "up", "down" and "unknown"
+	MTU       int
+	Speed     int    // Mbps, 0 if unknown (e.g. the link is down or virtual)
+	Duplex    string // "full", "half" or "unknown", empty if not supported
+}
+
+// ReadLinkStatus reads the status of the interface from {dir}/{name}.
+func ReadLinkStatus(dir, name string) (*LinkStatus, error) {
+	read := func(file string) (string, error) {
+		out, err := os.ReadFile(filepath.Join(dir, name, file))
+		return strings.TrimSpace(string(out)), err
+	}
+	state, err := read("operstate")
+	if err != nil {
+		return nil, err
+	}
+	status := &LinkStatus{OperState: state}
+	if s, err := read("mtu"); err == nil {
+		status.MTU, _ = strconv.Atoi(s)
+	}
+	// Reading speed and duplex fails with EINVAL while the link is down.
+	if s, err := read("speed"); err == nil {
+		if speed, err := strconv.Atoi(s); err == nil && speed > 0 {
+			status.Speed = speed
+		}
+	}
+	if s, err := read("duplex"); err == nil {
+		status.Duplex = s
+	}
+	return status, nil
+}
+
+// Bond is a bonding interface in /proc/net/bonding.
+type Bond struct {
+	Name      string      `json:"name"`
+	Mode      string      `json:"mode"`
+	MIIStatus string      `json:"miiStatus"`
+	Slaves    []BondSlave `json:"slaves"`
+}
+
+// BondSlave is a slave interface of Bond.
+type BondSlave struct {
+	Name             string `json:"name"`
+	MIIStatus        string `json:"miiStatus"`
+	Speed            string `json:"speed,omitempty"`
+	Duplex           string `json:"duplex,omitempty"`
+	LinkFailureCount int    `json:"linkFailureCount"`
+}
+
+// `/proc/net/bonding/bond0` sample:
+//  Bonding Mode: fault-tolerance (active-backup)
+//  Primary Slave: None
+//  Currently Active Slave: eth0
+//  MII Status: up
+//
+//  Slave Interface: eth0
+//  MII Status: up
+//  Speed: 1000 Mbps
+//  Duplex: full
+//  Link Failure Count: 0
+
+// ReadBonds reads the bonding interfaces in the directory in order of
+// the names. It returns no bonds if the directory does not exist, that
+// is, the bonding driver is not loaded.
+func ReadBonds(dir string) ([]*Bond, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var bonds []*Bond
+	for _, entry := range entries {
+		out, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		bonds = append(bonds, parseBond(entry.Name(), out))
+	}
+	sort.Slice(bonds, func(i, j int) bool {
+		return bonds[i].Name < bonds[j].Name
+	})
+	return bonds, nil
+}
+
+func parseBond(name string, out []byte) *Bond {
+	bond := &Bond{Name: name}
+	var slave *BondSlave
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch key {
+		case "Bonding Mode":
+			bond.Mode = value
+		case "Slave Interface":
+			bond.Slaves = append(bond.Slaves, BondSlave{Name: value})
+			slave = &bond.Slaves[len(bond.Slaves)-1]
+		case "MII Status":
+			// The first one is the status of the bond itself.
+			if slave == nil {
+				bond.MIIStatus = value
+			} else {
+				slave.MIIStatus = value
+			}
+		case "Speed":
+			if slave != nil {
+				slave.Speed = value
+			}
+		case "Duplex":
+			if slave != nil {
+				slave.Duplex = value
+			}
+		case "Link Failure Count":
+			if slave != nil {
+				slave.LinkFailureCount, _ = strconv.Atoi(value)
+			}
+		}
+	}
+	return bond
+}
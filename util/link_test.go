@@ -0,0 +1,66 @@
+package util
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadLinkStatus(t *testing.T) {
+	dir := filepath.Join("testdata", "net")
+	tests := []struct {
+		name   string
+		expect *LinkStatus
+	}{
+		{name: "eth0", expect: &LinkStatus{OperState: "up", MTU: 9000, Speed: 1000, Duplex: "full"}},
+		{name: "eth1", expect: &LinkStatus{OperState: "down", MTU: 1500}},
+	}
+	for _, tt := range tests {
+		status, err := ReadLinkStatus(dir, tt.name)
+		if err != nil {
+			t.Errorf("should not raise error: %v", err)
+			continue
+		}
+		if !reflect.DeepEqual(status, tt.expect) {
+			t.Errorf("status of %s should be %+v but %+v", tt.name, tt.expect, status)
+		}
+	}
+	if _, err := ReadLinkStatus(dir, "eth9"); err == nil {
+		t.Error("should raise error for a missing interface")
+	}
+}
+
+func TestReadBonds(t *testing.T) {
+	bonds, err := ReadBonds(filepath.Join("testdata", "bonding"))
+	if err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+	expected := []*Bond{
+		{
+			Name:      "bond0",
+			Mode:      "fault-tolerance (active-backup)",
+			MIIStatus: "up",
+			Slaves: []BondSlave{
+				{Name: "eth0", MIIStatus: "up", Speed: "1000 Mbps", Duplex: "full"},
+				{Name: "eth1", MIIStatus: "down", Speed: "Unknown", Duplex: "Unknown", LinkFailureCount: 3},
+			},
+		},
+		{
+			Name:      "bond1",
+			Mode:      "IEEE 802.3ad Dynamic link aggregation",
+			MIIStatus: "up",
+			Slaves: []BondSlave{
+				{Name: "eth2", MIIStatus: "up", Speed: "10000 Mbps", Duplex: "full"},
+				{Name: "eth3", MIIStatus: "up", Speed: "10000 Mbps", Duplex: "full", LinkFailureCount: 1},
+			},
+		},
+	}
+	if !reflect.DeepEqual(bonds, expected) {
+		t.Errorf("bonds should be %+v but %+v", expected, bonds)
+	}
+
+	bonds, err = ReadBonds(filepath.Join("testdata", "not-exist"))
+	if err != nil || bonds != nil {
+		t.Errorf("should return no bonds without error: %v, %v", bonds, err)
+	}
+}
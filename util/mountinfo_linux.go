@@ -0,0 +1,107 @@
+//go:build linux
+// +build linux
+
+package util
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// MountInfo is a mount point retrieved from /proc/self/mountinfo.
+type MountInfo struct {
+	Device     string // major:minor
+	Source     string
+	Mountpoint string
+	FSType     string
+	Options    []string
+}
+
+// ReadOnly returns whether the mount point is mounted read-only.
+// Both of the per-mount options and the per-superblock options are respected.
+func (m *MountInfo) ReadOnly() bool {
+	for _, opt := range m.Options {
+		if opt == "ro" {
+			return true
+		}
+	}
+	return false
+}
+
+// `/proc/self/mountinfo` sample:
+//  36 35 98:0 /mnt1 /mnt2 rw,noatime master:1 - ext3 /dev/root rw,errors=continue
+//  (1)(2)(3)   (4)   (5)      (6)      (7)   (8) (9)   (10)         (11)
+// (7) is zero or more optional fields terminated by the separator (8).
+
+const mountinfoPath = "/proc/self/mountinfo"
+
+// CollectMountInfo collects mount points from /proc/self/mountinfo
+func CollectMountInfo() ([]*MountInfo, error) {
+	out, err := os.ReadFile(mountinfoPath)
+	if err != nil {
+		return nil, err
+	}
+	return parseMountInfo(out), nil
+}
+
+func parseMountInfo(out []byte) []*MountInfo {
+	var mounts []*MountInfo
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		m, err := parseMountInfoLine(scanner.Text())
+		if err != nil {
+			logger.Warningf(err.Error())
+			continue
+		}
+		mounts = append(mounts, m)
+	}
+	return mounts
+}
+
+func parseMountInfoLine(line string) (*MountInfo, error) {
+	fields := strings.Fields(line)
+	sep := -1
+	for i := 6; i < len(fields); i++ {
+		if fields[i] == "-" {
+			sep = i
+			break
+		}
+	}
+	if sep < 0 || len(fields) < sep+3 {
+		return nil, fmt.Errorf("failed to parse mountinfo line: [%s]", line)
+	}
+	options := strings.Split(fields[5], ",")
+	if len(fields) > sep+3 {
+		options = append(options, strings.Split(fields[sep+3], ",")...)
+	}
+	return &MountInfo{
+		Device:     fields[2],
+		Source:     unescapeMountInfo(fields[sep+2]),
+		Mountpoint: unescapeMountInfo(fields[4]),
+		FSType:     fields[sep+1],
+		Options:    options,
+	}, nil
+}
+
+// unescapeMountInfo decodes octal escapes such as `\040` for a space.
+func unescapeMountInfo(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+4 <= len(s) {
+			if c, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
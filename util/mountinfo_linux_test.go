@@ -0,0 +1,44 @@
+//go:build linux
+// +build linux
+
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCollectMountInfo(t *testing.T) {
+	mounts, err := CollectMountInfo()
+	if err != nil {
+		t.Errorf("err should be nil but: %s", err)
+	}
+	if len(mounts) == 0 {
+		t.Errorf("mount points should be collected")
+	}
+}
+
+func TestParseMountInfo(t *testing.T) {
+	out := `22 28 0:21 / /sys rw,nosuid,nodev,noexec,relatime shared:7 - sysfs sysfs rw
+28 1 8:1 / / rw,relatime shared:1 - ext4 /dev/sda1 rw,errors=remount-ro
+40 28 8:2 / /mnt/my\040data ro,relatime shared:25 master:1 - xfs /dev/sdb1 rw,attr2
+41 28 8:3 / /var rw,relatime - ext4 /dev/sdc1 ro
+invalid line
+`
+	expect := []*MountInfo{
+		{Device: "0:21", Source: "sysfs", Mountpoint: "/sys", FSType: "sysfs", Options: []string{"rw", "nosuid", "nodev", "noexec", "relatime", "rw"}},
+		{Device: "8:1", Source: "/dev/sda1", Mountpoint: "/", FSType: "ext4", Options: []string{"rw", "relatime", "rw", "errors=remount-ro"}},
+		{Device: "8:2", Source: "/dev/sdb1", Mountpoint: "/mnt/my data", FSType: "xfs", Options: []string{"ro", "relatime", "rw", "attr2"}},
+		{Device: "8:3", Source: "/dev/sdc1", Mountpoint: "/var", FSType: "ext4", Options: []string{"rw", "relatime", "ro"}},
+	}
+	mounts := parseMountInfo([]byte(out))
+	if !reflect.DeepEqual(mounts, expect) {
+		t.Errorf("mounts should be %+v but %+v", expect, mounts)
+	}
+
+	for i, readOnly := range []bool{false, false, true, true} {
+		if mounts[i].ReadOnly() != readOnly {
+			t.Errorf("ReadOnly() of %s should be %t", mounts[i].Mountpoint, readOnly)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package util
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// MdstatPath is the path to the status of Linux software RAID (md) arrays
+const MdstatPath = "/proc/mdstat"
+
+// ZFSKstatPath is the directory of the kstats of ZFS on Linux
+const ZFSKstatPath = "/proc/spl/kstat/zfs"
+
+// MdArray is a Linux software RAID array in /proc/mdstat.
+type MdArray struct {
+	Name        string // e.g. "md0"
+	State       string // "active", "inactive" or "active (auto-read-only)"
+	Level       string // e.g. "raid1", empty for inactive arrays
+	Disks       int    // the number of disks of the array, 0 if unknown (e.g. raid0)
+	ActiveDisks int
+	FailedDisks int
+	SyncAction  string  // "resync", "recovery", "reshape" or "check" in progress
+	SyncPercent float64 // the progress of SyncAction
+}
+
+// Degraded returns whether the array is missing any disks.
+func (a *MdArray) Degraded() bool {
+	return a.ActiveDisks < a.Disks || a.FailedDisks > 0
+}
+
+// `/proc/mdstat` sample:
+//  Personalities : [raid1] [raid6] [raid5] [raid4]
+//  md1 : active raid5 sdc1[3] sdb2[1] sda2[0](F)
+//        2095104 blocks super 1.2 level 5, 512k chunk, algorithm 2 [3/2] [_UU]
+//        [=>...................]  recovery =  8.5% (89376/1047552) finish=0.5min speed=29792K/sec
+//
+//  unused devices: <none>
+
+var (
+	mdHeaderRegexp = regexp.MustCompile(`^(md\S*) : (active(?: \([^)]*\))?|inactive)(?: (\S+))?`)
+	mdDisksRegexp  = regexp.MustCompile(`\[(\d+)/(\d+)\] \[[U_]+\]`)
+	mdSyncRegexp   = regexp.MustCompile(`(resync|recovery|reshape|check)\s*=\s*([\d.]+)%`)
+)
+
+// ReadMdstat reads md arrays from the mdstat file. It returns no arrays
+// if the file does not exist, that is, the md driver is not loaded.
+func ReadMdstat(path string) ([]*MdArray, error) {
+	out, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseMdstat(out)
+}
+
+func parseMdstat(out []byte) ([]*MdArray, error) {
+	var arrays []*MdArray
+	var current *MdArray
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if m := mdHeaderRegexp.FindStringSubmatch(line); m != nil {
+			current = &MdArray{Name: m[1], State: m[2]}
+			if current.State != "inactive" {
+				current.Level = m[3]
+			}
+			current.FailedDisks = strings.Count(line, "(F)")
+			arrays = append(arrays, current)
+			continue
+		}
+		if current == nil || !strings.HasPrefix(line, " ") {
+			current = nil
+			continue
+		}
+		if m := mdDisksRegexp.FindStringSubmatch(line); m != nil {
+			current.Disks, _ = strconv.Atoi(m[1])
+			current.ActiveDisks, _ = strconv.Atoi(m[2])
+		}
+		if m := mdSyncRegexp.FindStringSubmatch(line); m != nil {
+			current.SyncAction = m[1]
+			percent, err := strconv.ParseFloat(m[2], 64)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse the progress of %s: %s", current.Name, err)
+			}
+			current.SyncPercent = percent
+		}
+	}
+	return arrays, scanner.Err()
+}
+
+// ReadZpoolStates reads the states of ZFS pools such as "ONLINE" and
+// "DEGRADED" from {dir}/{pool}/state (ZFS on Linux 0.8+). It returns
+// no pools if the directory does not exist, that is, ZFS is not loaded.
+func ReadZpoolStates(dir string) (map[string]string, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	states := make(map[string]string)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		out, err := os.ReadFile(filepath.Join(dir, entry.Name(), "state"))
+		if err != nil {
+			// the directories other than pools, such as "zfs/fm"
+			continue
+		}
+		states[entry.Name()] = strings.TrimSpace(string(out))
+	}
+	return states, nil
+}
@@ -0,0 +1,58 @@
+package util
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadMdstat(t *testing.T) {
+	arrays, err := ReadMdstat(filepath.Join("testdata", "mdstat"))
+	if err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+	expected := []*MdArray{
+		{Name: "md2", State: "active", Level: "raid0"},
+		{Name: "md1", State: "active", Level: "raid5", Disks: 3, ActiveDisks: 2, FailedDisks: 1, SyncAction: "recovery", SyncPercent: 8.5},
+		{Name: "md0", State: "active", Level: "raid1", Disks: 2, ActiveDisks: 2, SyncAction: "check", SyncPercent: 52.3},
+		{Name: "md127", State: "inactive"},
+	}
+	if !reflect.DeepEqual(arrays, expected) {
+		for _, a := range arrays {
+			t.Logf("%+v", a)
+		}
+		t.Errorf("unexpected arrays")
+	}
+	degraded := make(map[string]bool)
+	for _, a := range arrays {
+		degraded[a.Name] = a.Degraded()
+	}
+	if !reflect.DeepEqual(degraded, map[string]bool{"md0": false, "md1": true, "md2": false, "md127": false}) {
+		t.Errorf("unexpected degraded arrays: %v", degraded)
+	}
+}
+
+func TestReadMdstat_notExist(t *testing.T) {
+	arrays, err := ReadMdstat(filepath.Join("testdata", "not-exist"))
+	if err != nil || arrays != nil {
+		t.Errorf("should return no arrays without error: %v, %v", arrays, err)
+	}
+}
+
+func TestReadZpoolStates(t *testing.T) {
+	// testdata/zfs has the kstat file arcstats and the directory fm without
+	// state besides the pools, which should be skipped as non-pool entries.
+	states, err := ReadZpoolStates(filepath.Join("testdata", "zfs"))
+	if err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+	expected := map[string]string{"tank": "ONLINE", "backup": "DEGRADED"}
+	if !reflect.DeepEqual(states, expected) {
+		t.Errorf("states should be %v but %v", expected, states)
+	}
+
+	states, err = ReadZpoolStates(filepath.Join("testdata", "not-exist"))
+	if err != nil || states != nil {
+		t.Errorf("should return no pools without error: %v, %v", states, err)
+	}
+}
@@ -0,0 +1,18 @@
+package util
+
+import (
+	"errors"
+	"time"
+)
+
+// TimeSync is the state of the synchronization of the system clock
+// by NTP daemons such as chronyd and ntpd.
+type TimeSync struct {
+	Synchronized   bool
+	EstimatedError time.Duration
+	MaxError       time.Duration
+}
+
+// ErrTimeSyncNotSupported is returned by ReadTimeSync on the platforms
+// other than Linux.
+var ErrTimeSyncNotSupported = errors.New("reading the state of time synchronization is not supported")
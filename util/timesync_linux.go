@@ -0,0 +1,29 @@
+//go:build linux
+// +build linux
+
+package util
+
+import (
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// ReadTimeSync reads the state of the kernel clock with adjtimex(2).
+func ReadTimeSync() (*TimeSync, error) {
+	var tx unix.Timex // Modes is zero, which only reads the state
+	state, err := unix.Adjtimex(&tx)
+	if err != nil {
+		return nil, err
+	}
+	return newTimeSync(state, int64(tx.Status), int64(tx.Esterror), int64(tx.Maxerror)), nil
+}
+
+// newTimeSync converts the results of adjtimex(2), where the errors are in microseconds.
+func newTimeSync(state int, status, esterror, maxerror int64) *TimeSync {
+	return &TimeSync{
+		Synchronized:   state != unix.TIME_ERROR && status&unix.STA_UNSYNC == 0,
+		EstimatedError: time.Duration(esterror) * time.Microsecond,
+		MaxError:       time.Duration(maxerror) * time.Microsecond,
+	}
+}
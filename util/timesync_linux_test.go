@@ -0,0 +1,40 @@
+//go:build linux
+// +build linux
+
+package util
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestReadTimeSync(t *testing.T) {
+	ts, err := ReadTimeSync()
+	if err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+	t.Logf("time sync: %+v", ts)
+}
+
+func TestNewTimeSync(t *testing.T) {
+	tests := []struct {
+		state  int
+		status int64
+		expect bool
+	}{
+		{state: unix.TIME_OK, status: unix.STA_PLL, expect: true},
+		{state: unix.TIME_OK, status: unix.STA_PLL | unix.STA_UNSYNC, expect: false},
+		{state: unix.TIME_ERROR, status: unix.STA_PLL, expect: false},
+	}
+	for _, tt := range tests {
+		ts := newTimeSync(tt.state, tt.status, 1500, 16000000)
+		if ts.Synchronized != tt.expect {
+			t.Errorf("synchronized should be %v for state=%d status=%#x", tt.expect, tt.state, tt.status)
+		}
+		if ts.EstimatedError != 1500*time.Microsecond || ts.MaxError != 16*time.Second {
+			t.Errorf("unexpected errors: %+v", ts)
+		}
+	}
+}
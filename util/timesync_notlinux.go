@@ -0,0 +1,9 @@
+//go:build !linux
+// +build !linux
+
+package util
+
+// ReadTimeSync returns ErrTimeSyncNotSupported.
+func ReadTimeSync() (*TimeSync, error) {
+	return nil, ErrTimeSyncNotSupported
+}